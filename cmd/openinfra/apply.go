@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Ilya-Guyduk/openinfra/executor"
+)
+
+// applyResult — результат для компонента в JSON-выводе apply.
+type applyResult struct {
+	executor.Result
+	Error string `json:"error,omitempty"`
+}
+
+// runApply выполняет действие для всех компонентов с учётом зависимостей.
+func runApply(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("apply", stderr)
+	file := fs.String("f", defaultSpecFile, "файл спецификации")
+	flags := addSpecFlags(fs)
+	asJSON := fs.Bool("json", false, "вывести отчёт в JSON")
+	timeout := fs.Duration("timeout", 0, "ограничение времени каждого вызова, например 30s")
+	concurrency := fs.Int("concurrency", executor.DefaultConcurrency, "число одновременно выполняемых действий")
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return exitUsage
+	}
+	if len(rest) != 1 {
+		fmt.Fprintln(stderr, "Использование: openinfra apply [-f FILE] ACTION")
+		return exitUsage
+	}
+	action := rest[0]
+
+	spec := loadSpec(*file, flags, stderr)
+	if spec == nil {
+		return exitError
+	}
+	ex, err := executor.New(spec,
+		executor.WithConcurrency(*concurrency),
+		executor.WithExecOptions(execOptions(*timeout)...))
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	report, err := ex.Run(ctx, action)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+
+	code := exitOK
+	if report.Failed() {
+		code = exitError
+	}
+
+	if *asJSON {
+		results := make([]applyResult, len(report.Results))
+		for i, res := range report.Results {
+			results[i] = applyResult{Result: res}
+			if res.Err != nil {
+				results[i].Error = res.Err.Error()
+			}
+		}
+		out := struct {
+			Action  string        `json:"action"`
+			Results []applyResult `json:"results"`
+		}{report.Action, results}
+		if jsonResult(writeJSON(stdout, out), stderr) != exitOK {
+			return exitError
+		}
+		return code
+	}
+
+	tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "COMPONENT\tSTATUS\tDURATION\tDETAILS")
+	for _, res := range report.Results {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", res.Component, res.Status,
+			res.Duration.Round(time.Millisecond), resultDetails(res))
+	}
+	tw.Flush()
+	return code
+}
+
+// resultDetails поясняет статус компонента в таблице apply.
+func resultDetails(res executor.Result) string {
+	switch {
+	case res.Err != nil:
+		return res.Err.Error()
+	case res.BlockedBy != "":
+		return "не выполнена зависимость " + res.BlockedBy
+	case res.Capability != "":
+		return fmt.Sprintf("%s.%s", res.Provider, res.Capability)
+	}
+	return ""
+}
@@ -0,0 +1,478 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Ilya-Guyduk/openinfra/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const infraSpec = `openinfra: 1.1.0
+providers:
+  - name: vbox
+    type: hypervisor
+    connection:
+      protocol: https
+      host: 10.0.0.5
+      port: 18083
+      authentication:
+        method: password
+        username: admin
+        password: secret
+    capabilities:
+      - name: start
+        method: POST
+        endpoint: /vms/{name}/start
+  - name: cloud
+    type: cloud
+    connection:
+      endpoint: https://api.example.com
+components:
+  - name: vm
+    type: virtual_machine
+    provider: vbox
+    actions:
+      - name: start
+    dependencies:
+      - depends_on: [net]
+  - name: net
+    type: network
+    provider: cloud
+  - name: dns
+    type: dns
+    provider: cloud
+dependencies:
+  - component: dns
+    depends_on: [vm, net]
+`
+
+func TestParseArgs(t *testing.T) {
+	fs := newFlagSet("test", os.Stderr)
+	verbose := fs.Bool("v", false, "")
+	params := paramFlag{}
+	fs.Var(params, "param", "")
+
+	rest, err := parseArgs(fs, []string{"vbox", "--param", "id=7", "start", "-v", "--param", `ids=[1,2]`, "--", "-x"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"vbox", "start", "-x"}, rest)
+	assert.True(t, *verbose)
+	assert.Equal(t, paramFlag{"id": "7", "ids": []interface{}{1.0, 2.0}}, params)
+
+	assert.Error(t, params.Set("novalue"))
+}
+
+func TestValidate(t *testing.T) {
+	good := writeFile(t, "openinfra.yaml", infraSpec)
+	bad := writeFile(t, "bad.yaml", `openinfra: 1.1.0
+components:
+  - name: vm
+    type: vm
+    provider: ghost
+`)
+	cyclic := writeFile(t, "cyclic.yaml", `openinfra: 1.1.0
+providers: [{name: p, type: t}]
+components:
+  - {name: a, type: t, provider: p, dependencies: [{depends_on: [b]}]}
+  - {name: b, type: t, provider: p, dependencies: [{depends_on: [a]}]}
+`)
+
+	code, stdout, _ := runCLI("validate", good)
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, good+": ok\n", stdout)
+
+	code, stdout, _ = runCLI("validate", good, bad, cyclic)
+	assert.Equal(t, exitError, code)
+	assert.Contains(t, stdout, bad+`:5:15: компонент "vm" ссылается на несуществующего провайдера "ghost"`)
+	assert.Contains(t, stdout, cyclic+": обнаружен цикл зависимостей: a -> b -> a")
+
+	code, stdout, _ = runCLI("validate", "--json", bad, "missing.yaml")
+	assert.Equal(t, exitError, code)
+	var reports []fileReport
+	require.NoError(t, json.Unmarshal([]byte(stdout), &reports))
+	require.Len(t, reports, 2)
+	assert.False(t, reports[0].Valid)
+	assert.Equal(t, "unknown_provider", string(reports[0].Errors[0].Code))
+	assert.Equal(t, "components[0].provider", reports[0].Errors[0].Path)
+	assert.Equal(t, 5, reports[0].Errors[0].Line)
+	assert.Equal(t, codeParse, reports[1].Errors[0].Code)
+	assert.Contains(t, reports[1].Errors[0].Message, "не найден")
+}
+
+func TestValidateStrict(t *testing.T) {
+	path := writeFile(t, "typo.yaml", `openinfra: 1.1.0
+providers:
+  - name: p
+    type: t
+    capabilites: []
+`)
+
+	code, stdout, _ := runCLI("validate", path)
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, path+": ok\n", stdout)
+
+	code, stdout, _ = runCLI("validate", "--strict", path)
+	assert.Equal(t, exitError, code)
+	assert.Equal(t, path+":5:5: неизвестное поле capabilites, возможно, имелось в виду capabilities\n", stdout)
+
+	code, stdout, _ = runCLI("validate", "--strict", "--json", path)
+	assert.Equal(t, exitError, code)
+	var reports []fileReport
+	require.NoError(t, json.Unmarshal([]byte(stdout), &reports))
+	require.Len(t, reports[0].Errors, 1)
+	assert.Equal(t, parser.CodeUnknownField, reports[0].Errors[0].Code)
+	assert.Equal(t, "providers[0].capabilites", reports[0].Errors[0].Path)
+}
+
+func TestValidateReportsAllMalformedEntries(t *testing.T) {
+	path := writeFile(t, "broken.yaml", `openinfra: 1.1.0
+providers:
+  - name: p
+    type: t
+    connection:
+      port: high
+components:
+  - name: vm
+    actions: start
+  - name: net
+    type: network
+    provider: p
+`)
+
+	code, stdout, _ := runCLI("validate", path)
+	assert.Equal(t, exitError, code)
+	assert.Equal(t, path+":3:5: не удалось разобрать провайдер \"p\": line 6: cannot unmarshal !!str `high` into int\n"+
+		path+":8:5: не удалось разобрать компонент \"vm\": line 9: cannot unmarshal !!str `start` into []parser.Action\n",
+		stdout)
+}
+
+func TestValidateChecksRecoveredSpec(t *testing.T) {
+	path := writeFile(t, "partial.yaml", `openinfra: 1.1.0
+providers:
+  - name: p
+    type: t
+    connection:
+      port: high
+components:
+  - name: vm
+    type: virtual_machine
+    provider: p
+  - name: db
+    type: database
+    provider: missing
+`)
+
+	// Ссылка на пропущенный провайдер p не считается ошибкой, а на
+	// необъявленный missing — считается
+	code, stdout, _ := runCLI("validate", path)
+	assert.Equal(t, exitError, code)
+	assert.Equal(t, path+":3:5: не удалось разобрать провайдер \"p\": line 6: cannot unmarshal !!str `high` into int\n"+
+		path+":13:15: компонент \"db\" ссылается на несуществующего провайдера \"missing\"\n",
+		stdout)
+
+	path = writeFile(t, "cycle.yaml", `openinfra: 1.1.0
+providers:
+  - name: p
+    type: t
+  - name: broken
+    type: [t]
+components:
+  - name: a
+    type: t
+    provider: p
+    dependencies:
+      - depends_on: [b]
+  - name: b
+    type: t
+    provider: p
+    dependencies:
+      - depends_on: [a]
+`)
+
+	code, stdout, _ = runCLI("validate", "--json", path)
+	assert.Equal(t, exitError, code)
+	var reports []fileReport
+	require.NoError(t, json.Unmarshal([]byte(stdout), &reports))
+	require.Len(t, reports[0].Errors, 2)
+	assert.Equal(t, parser.CodeInvalidValue, reports[0].Errors[0].Code)
+	assert.Equal(t, codeDependencyCycle, reports[0].Errors[1].Code)
+}
+
+func TestValidateStream(t *testing.T) {
+	path := writeFile(t, "envs.yaml", `openinfra: 1.1.0
+components:
+  - name: vm
+---
+openinfra: 1.1.0
+components:
+  - name: vm
+    provider: missing
+`)
+
+	code, stdout, _ := runCLI("validate", path)
+	assert.Equal(t, exitError, code)
+	assert.Contains(t, stdout, path+":8:15: ")
+	assert.NotContains(t, stdout, ": ok")
+
+	path = writeFile(t, "spec.json", `{"openinfra": "1.1.0",
+  "providers": [{"name": "p", "type": "t"}],
+  "components": [{"name": "vm", "type": "virtual_machine", "provider": "p"}]}`)
+	code, stdout, _ = runCLI("validate", path)
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, path+": ok\n", stdout)
+}
+
+func TestValidateIncludeRoot(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "envs"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "shared"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "shared", "common.yaml"),
+		[]byte("providers:\n  - name: p\n    type: t\n"), 0o644))
+	prod := filepath.Join(dir, "envs", "prod.yaml")
+	require.NoError(t, os.WriteFile(prod,
+		[]byte("openinfra: 1.1.0\nincludes: [../shared/common.yaml]\ncomponents:\n  - name: vm\n    type: t\n    provider: p\n"), 0o644))
+
+	code, stdout, _ := runCLI("validate", prod)
+	assert.Equal(t, exitError, code)
+	assert.Contains(t, stdout, "выходит за пределы каталога спецификации")
+
+	code, stdout, _ = runCLI("validate", "--include-root", dir, prod)
+	assert.Equal(t, exitOK, code, stdout)
+
+	code, stdout, _ = runCLI("components", "-f", prod, "--include-root", dir)
+	assert.Equal(t, exitOK, code)
+	assert.Contains(t, stdout, "vm")
+}
+
+func TestFmt(t *testing.T) {
+	messy := "openinfra: 1.1.0\nproviders:\n    # гипервизор\n    -   name: vbox\n        type: hypervisor\n"
+	path := writeFile(t, "messy.yaml", messy)
+	want := "openinfra: 1.1.0\nproviders:\n  # гипервизор\n  - name: vbox\n    type: hypervisor\n"
+
+	code, stdout, _ := runCLI("fmt", path)
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, want, stdout)
+
+	code, _, _ = runCLI("fmt", "-w", path)
+	assert.Equal(t, exitOK, code)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, want, string(data))
+
+	code, stdout, _ = runCLI("fmt", "--check", path)
+	assert.Equal(t, exitOK, code)
+	assert.Empty(t, stdout)
+
+	unordered := writeFile(t, "unordered.yaml", "providers:\n  - type: t\n    name: p\nopeninfra: 1.1.0\n")
+	code, stdout, _ = runCLI("fmt", "--check", path, unordered)
+	assert.Equal(t, exitError, code)
+	assert.Equal(t, unordered+"\n", stdout)
+	data, err = os.ReadFile(unordered)
+	require.NoError(t, err)
+	assert.Equal(t, "providers:\n  - type: t\n    name: p\nopeninfra: 1.1.0\n", string(data))
+
+	code, _, _ = runCLI("fmt", "-w", "--check", path)
+	assert.Equal(t, exitUsage, code)
+
+	code, _, stderr := runCLI("fmt", writeFile(t, "broken.yaml", "a: [b"))
+	assert.Equal(t, exitError, code)
+	assert.Contains(t, stderr, "некорректное форматирование YAML")
+}
+
+func TestFmtWriteKeepsMode(t *testing.T) {
+	path := writeFile(t, "secret.yaml", "providers:\n  - type: t\n    name: p\nopeninfra: 1.1.0\n")
+	require.NoError(t, os.Chmod(path, 0o600))
+
+	code, _, _ := runCLI("fmt", "-w", path)
+	assert.Equal(t, exitOK, code)
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "openinfra: 1.1.0\nproviders:\n  - name: p\n    type: t\n", string(data))
+}
+
+func TestFmtStreamAndJSON(t *testing.T) {
+	stream := writeFile(t, "envs.yaml", "openinfra: 1.1.0\n---\n'openinfra': 1.1.0\n")
+	code, stdout, _ := runCLI("fmt", "--check", stream)
+	assert.Equal(t, exitError, code)
+	assert.Equal(t, stream+"\n", stdout)
+
+	code, _, _ = runCLI("fmt", "-w", stream)
+	assert.Equal(t, exitOK, code)
+	data, err := os.ReadFile(stream)
+	require.NoError(t, err)
+	assert.Equal(t, "openinfra: 1.1.0\n---\nopeninfra: 1.1.0\n", string(data))
+
+	code, _, _ = runCLI("fmt", "--check", stream)
+	assert.Equal(t, exitOK, code)
+
+	original := `{"openinfra": "1.1.0"}`
+	spec := writeFile(t, "spec.json", original)
+	code, stdout, _ = runCLI("fmt", spec)
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, "openinfra: 1.1.0\n", stdout)
+
+	for _, flag := range []string{"-w", "--check"} {
+		code, _, stderr := runCLI("fmt", flag, spec)
+		assert.Equal(t, exitError, code)
+		assert.Contains(t, stderr, "fmt записывает YAML и не меняет файлы JSON")
+	}
+	data, err = os.ReadFile(spec)
+	require.NoError(t, err)
+	assert.Equal(t, original, string(data))
+}
+
+func TestGraph(t *testing.T) {
+	path := writeFile(t, "openinfra.yaml", infraSpec)
+
+	code, stdout, _ := runCLI("graph", "-f", path)
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, `WAVE  COMPONENT  DEPENDS ON
+1     net        
+2     vm         net
+3     dns        net, vm
+`, stdout)
+
+	code, stdout, _ = runCLI("graph", "-f", path, "--json")
+	assert.Equal(t, exitOK, code)
+	var nodes []graphNode
+	require.NoError(t, json.Unmarshal([]byte(stdout), &nodes))
+	assert.Equal(t, []graphNode{
+		{Name: "net", Wave: 1, DependsOn: []string{}},
+		{Name: "vm", Wave: 2, DependsOn: []string{"net"}},
+		{Name: "dns", Wave: 3, DependsOn: []string{"net", "vm"}},
+	}, nodes)
+
+	code, stdout, _ = runCLI("graph", "-f", path, "--dot")
+	assert.Equal(t, exitOK, code)
+	assert.Contains(t, stdout, "digraph openinfra {\n")
+	assert.Contains(t, stdout, `  "dns" -> "vm";`)
+
+	code, _, stderr := runCLI("graph", "-f", "missing.yaml")
+	assert.Equal(t, exitError, code)
+	assert.Contains(t, stderr, "файл missing.yaml не найден")
+}
+
+func TestProvidersAndComponents(t *testing.T) {
+	path := writeFile(t, "openinfra.yaml", infraSpec)
+
+	code, stdout, _ := runCLI("providers", "-f", path)
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, `NAME   TYPE        ENDPOINT                 AUTH      CAPABILITIES
+vbox   hypervisor  https://10.0.0.5:18083   password  start
+cloud  cloud       https://api.example.com            
+`, stdout)
+	assert.NotContains(t, stdout, "secret")
+
+	code, stdout, _ = runCLI("providers", "-f", path, "--json", "--type", "cloud")
+	assert.Equal(t, exitOK, code)
+	var providers []providerInfo
+	require.NoError(t, json.Unmarshal([]byte(stdout), &providers))
+	assert.Equal(t, []providerInfo{{
+		Name:         "cloud",
+		Type:         "cloud",
+		Endpoint:     "https://api.example.com",
+		Capabilities: []string{},
+	}}, providers)
+
+	code, stdout, _ = runCLI("components", "-f", path)
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, `NAME  TYPE             PROVIDER  ACTIONS  DEPENDS ON
+vm    virtual_machine  vbox      start    net
+net   network          cloud              
+dns   dns              cloud              net, vm
+`, stdout)
+
+	code, stdout, _ = runCLI("components", "-f", path, "--json", "--provider", "vbox")
+	assert.Equal(t, exitOK, code)
+	var components []componentInfo
+	require.NoError(t, json.Unmarshal([]byte(stdout), &components))
+	require.Len(t, components, 1)
+	assert.Equal(t, []string{"net"}, components[0].DependsOn)
+}
+
+func TestMerge(t *testing.T) {
+	base := writeFile(t, "base.yaml", infraSpec)
+	prod := writeFile(t, "prod.yaml", `providers:
+  - name: vbox
+    connection:
+      host: 10.1.0.5
+components:
+  - name: dns
+    $patch: delete
+dependencies:
+  - component: dns
+    $patch: delete
+`)
+
+	code, stdout, stderr := runCLI("merge", base, prod)
+	assert.Equal(t, exitOK, code, stderr)
+	assert.Contains(t, stdout, "host: 10.1.0.5")
+	assert.NotContains(t, stdout, "dns")
+
+	out := filepath.Join(t.TempDir(), "effective.yaml")
+	code, _, _ = runCLI("merge", "-o", out, base, prod)
+	assert.Equal(t, exitOK, code)
+	data, err := os.ReadFile(out)
+	require.NoError(t, err)
+	assert.Equal(t, stdout, string(data))
+
+	// Итоговая спецификация проверяется, ошибка указывает на наложение
+	broken := writeFile(t, "broken.yaml", "components:\n  - name: vm\n    provider: nope\n")
+	code, stdout, stderr = runCLI("merge", base, broken)
+	assert.Equal(t, exitError, code)
+	assert.Empty(t, stdout)
+	assert.Contains(t, stderr, broken+":3:15")
+
+	code, _, _ = runCLI("merge")
+	assert.Equal(t, exitUsage, code)
+}
+
+func TestVariables(t *testing.T) {
+	path := writeFile(t, "openinfra.yaml", `openinfra: 1.1.0
+variables:
+  host: 10.0.0.5
+  cpu: 2
+providers:
+  - name: vbox
+    type: hypervisor
+    connection:
+      protocol: https
+      host: ${var.host}
+components:
+  - name: vm
+    type: virtual_machine
+    provider: vbox
+    properties:
+      cpu: ${var.cpu}
+      image: ${var.image}
+`)
+
+	code, _, stderr := runCLI("components", "-f", path)
+	assert.Equal(t, exitError, code)
+	assert.Contains(t, stderr, "переменная image не объявлена в разделе variables")
+
+	code, stdout, stderr := runCLI("components", "-f", path, "--json",
+		"--var", "cpu=4", "--var", "image=ubuntu-22.04")
+	assert.Equal(t, exitOK, code, stderr)
+	var components []componentInfo
+	require.NoError(t, json.Unmarshal([]byte(stdout), &components))
+	require.Len(t, components, 1)
+	assert.Equal(t, map[string]interface{}{"cpu": float64(4), "image": "ubuntu-22.04"}, components[0].Properties)
+
+	code, stdout, _ = runCLI("providers", "-f", path, "--var", "host=192.168.0.9", "--var", "image=x")
+	assert.Equal(t, exitOK, code)
+	assert.Contains(t, stdout, "https://192.168.0.9")
+
+	code, stdout, _ = runCLI("validate", "--var", "image=x", path)
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, path+": ok\n", stdout)
+
+	code, _, _ = runCLI("validate", "--var", "broken", path)
+	assert.Equal(t, exitUsage, code)
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/Ilya-Guyduk/openinfra/parser"
+)
+
+// execOutput — результат вызова возможности для вывода в JSON.
+type execOutput struct {
+	Provider   string      `json:"provider"`
+	Capability string      `json:"capability"`
+	StatusCode int         `json:"status_code,omitempty"`
+	Body       string      `json:"body,omitempty"`
+	JSON       interface{} `json:"json,omitempty"`
+	Error      string      `json:"error,omitempty"`
+}
+
+// runExec вызывает возможность провайдера с параметрами из --param.
+func runExec(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("exec", stderr)
+	file := fs.String("f", defaultSpecFile, "файл спецификации")
+	flags := addSpecFlags(fs)
+	asJSON := fs.Bool("json", false, "вывести результат в JSON")
+	timeout := fs.Duration("timeout", 0, "ограничение времени выполнения, например 30s")
+	params := paramFlag{}
+	fs.Var(params, "param", "параметр возможности name=value, можно указывать несколько раз")
+	fs.Var(params, "p", "сокращение для --param")
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return exitUsage
+	}
+	if len(rest) != 2 {
+		fmt.Fprintln(stderr, "Использование: openinfra exec [-f FILE] PROVIDER CAPABILITY [--param name=value]...")
+		return exitUsage
+	}
+	providerName, capability := rest[0], rest[1]
+
+	spec := loadSpec(*file, flags, stderr)
+	if spec == nil {
+		return exitError
+	}
+	provider, err := spec.GetProviderByName(providerName)
+	if err != nil {
+		fmt.Fprintf(stderr, "провайдер %q не найден в %s\n", providerName, *file)
+		return exitError
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	result, err := provider.ExecuteCapabilityContext(ctx, capability, params, execOptions(*timeout)...)
+
+	if *asJSON {
+		out := execOutput{Provider: providerName, Capability: capability}
+		if result != nil {
+			out.StatusCode = result.StatusCode
+			out.JSON = result.JSON
+			if out.JSON == nil {
+				out.Body = result.String()
+			}
+		}
+		if err != nil {
+			out.Error = err.Error()
+		}
+		if jsonResult(writeJSON(stdout, out), stderr) != exitOK || err != nil {
+			return exitError
+		}
+		return exitOK
+	}
+
+	if result != nil && len(result.Body) > 0 {
+		stdout.Write(result.Body)
+		if result.Body[len(result.Body)-1] != '\n' {
+			fmt.Fprintln(stdout)
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+	return exitOK
+}
+
+// execOptions возвращает опции выполнения для флага --timeout.
+// Спецификация — локальный файл пользователя, поэтому ссылки ${env:NAME}
+// и ${file:path} разрешаются.
+func execOptions(timeout time.Duration) []parser.ExecOption {
+	opts := []parser.ExecOption{parser.WithLocalSecrets()}
+	if timeout > 0 {
+		opts = append(opts, parser.WithTimeout(timeout))
+	}
+	return opts
+}
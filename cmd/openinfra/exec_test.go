@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// apiSpec возвращает спецификацию с провайдером, обращающимся к url.
+func apiSpec(url string) string {
+	return `openinfra: 1.1.0
+providers:
+  - name: api
+    type: cloud
+    connection:
+      endpoint: ` + url + `
+    capabilities:
+      - name: get_vm
+        method: GET
+        endpoint: /vms/{id}
+        parameters:
+          - {name: id, type: integer, required: true}
+          - {name: fields, type: array}
+      - name: create
+        method: POST
+        endpoint: /vms
+components:
+  - name: net
+    type: network
+    provider: api
+    actions: [{name: create}]
+  - name: vm
+    type: virtual_machine
+    provider: api
+    actions: [{name: create}]
+    dependencies: [{depends_on: [net]}]
+`
+}
+
+func TestExec(t *testing.T) {
+	var gotURL string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotURL = r.URL.String()
+		if r.URL.Path == "/vms/404" {
+			http.Error(w, "no such vm", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"id": 7, "state": "running"}`)
+	}))
+	defer srv.Close()
+	path := writeFile(t, "openinfra.yaml", apiSpec(srv.URL))
+
+	code, stdout, _ := runCLI("exec", "-f", path, "api", "get_vm", "--param", "id=7", "-p", `fields=["state","id"]`)
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, "/vms/7?fields=state&fields=id", gotURL)
+	assert.Equal(t, `{"id": 7, "state": "running"}`+"\n", stdout)
+
+	code, stdout, _ = runCLI("exec", "-f", path, "--json", "api", "get_vm", "--param", "id=7")
+	assert.Equal(t, exitOK, code)
+	var out execOutput
+	require.NoError(t, json.Unmarshal([]byte(stdout), &out))
+	assert.Equal(t, 200, out.StatusCode)
+	assert.Equal(t, map[string]interface{}{"id": 7.0, "state": "running"}, out.JSON)
+
+	code, stdout, stderr := runCLI("exec", "-f", path, "api", "get_vm", "--param", "id=404")
+	assert.Equal(t, exitError, code)
+	assert.Equal(t, "no such vm\n", stdout)
+	assert.Contains(t, stderr, "вернула статус 404 Not Found")
+
+	code, _, stderr = runCLI("exec", "-f", path, "api", "get_vm", "--param", "id=seven")
+	assert.Equal(t, exitError, code)
+	assert.Contains(t, stderr, "id")
+
+	code, _, stderr = runCLI("exec", "-f", path, "ghost", "get_vm")
+	assert.Equal(t, exitError, code)
+	assert.Contains(t, stderr, `провайдер "ghost" не найден`)
+
+	code, _, _ = runCLI("exec", "-f", path, "api")
+	assert.Equal(t, exitUsage, code)
+	code, _, _ = runCLI("exec", "-f", path, "api", "get_vm", "--param", "novalue")
+	assert.Equal(t, exitUsage, code)
+}
+
+func TestApply(t *testing.T) {
+	// Первый вызов create успешен, следующие упираются в квоту
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls%2 == 1 {
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		http.Error(w, "quota exceeded", http.StatusConflict)
+	}))
+	defer srv.Close()
+	path := writeFile(t, "openinfra.yaml", apiSpec(srv.URL))
+
+	// net выполняется раньше зависящего от него vm
+	code, stdout, _ := runCLI("apply", "-f", path, "--concurrency", "1", "create")
+	assert.Equal(t, exitError, code)
+	assert.Equal(t, 2, calls)
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	require.Len(t, lines, 3)
+	assert.Regexp(t, `^COMPONENT\s+STATUS\s+DURATION\s+DETAILS$`, lines[0])
+	assert.Regexp(t, `^net\s+succeeded\s+\S+\s+api\.create$`, lines[1])
+	assert.Regexp(t, `^vm\s+failed\s+\S+\s+.*quota exceeded$`, lines[2])
+
+	code, stdout, _ = runCLI("apply", "-f", path, "--json", "create")
+	assert.Equal(t, exitError, code)
+	var report struct {
+		Action  string `json:"action"`
+		Results []struct {
+			Component string `json:"component"`
+			Status    string `json:"status"`
+			Error     string `json:"error"`
+		} `json:"results"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(stdout), &report))
+	assert.Equal(t, "create", report.Action)
+	require.Len(t, report.Results, 2)
+	assert.Equal(t, "succeeded", report.Results[0].Status)
+	assert.Equal(t, "failed", report.Results[1].Status)
+	assert.Contains(t, report.Results[1].Error, "409 Conflict")
+
+	// Действие, которого нет ни у одного компонента, — не ошибка
+	code, _, _ = runCLI("apply", "-f", path, "destroy")
+	assert.Equal(t, exitOK, code)
+
+	code, _, _ = runCLI("apply", "-f", path)
+	assert.Equal(t, exitUsage, code)
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Ilya-Guyduk/openinfra/parser"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultSpecFile — файл спецификации, если -f не указан.
+const defaultSpecFile = "openinfra.yaml"
+
+// newFlagSet создаёт набор флагов подкоманды, который пишет ошибки в stderr.
+func newFlagSet(name string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	return fs
+}
+
+// parseArgs разбирает флаги вперемешку с позиционными аргументами
+// (exec vbox start --param id=1) и возвращает позиционные аргументы.
+// Всё после -- считается позиционными аргументами.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		rest := fs.Args()
+		if len(rest) == 0 {
+			return positional, nil
+		}
+		if len(rest) < len(args) && args[len(args)-len(rest)-1] == "--" {
+			return append(positional, rest...), nil
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+}
+
+// paramFlag собирает повторяющийся флаг --param name=value. Значения,
+// похожие на JSON-массив или объект, разбираются как JSON, остальные
+// передаются строками: к типу параметра их приводит ValidateParameters.
+type paramFlag map[string]interface{}
+
+func (p paramFlag) String() string {
+	return fmt.Sprint(map[string]interface{}(p))
+}
+
+func (p paramFlag) Set(s string) error {
+	name, value, found := strings.Cut(s, "=")
+	if !found || name == "" {
+		return fmt.Errorf("ожидается name=value, получено %q", s)
+	}
+	if strings.HasPrefix(value, "[") || strings.HasPrefix(value, "{") {
+		var v interface{}
+		if err := json.Unmarshal([]byte(value), &v); err == nil {
+			p[name] = v
+			return nil
+		}
+	}
+	p[name] = value
+	return nil
+}
+
+// varFlag собирает повторяющийся флаг --var name=value со значениями
+// переменных для выражений ${var.name}. Значение получает тип по правилам
+// YAML: 8080 — число, true — логическое, [a, b] — список.
+type varFlag map[string]interface{}
+
+func (v varFlag) String() string {
+	return fmt.Sprint(map[string]interface{}(v))
+}
+
+func (v varFlag) Set(s string) error {
+	name, value, found := strings.Cut(s, "=")
+	if !found || name == "" {
+		return fmt.Errorf("ожидается name=value, получено %q", s)
+	}
+	var typed interface{}
+	if err := yaml.Unmarshal([]byte(value), &typed); err != nil || typed == nil {
+		typed = value
+	}
+	v[name] = typed
+	return nil
+}
+
+// specFlags — флаги разбора спецификации, общие для команд.
+type specFlags struct {
+	vars        varFlag
+	includeRoot string
+}
+
+// addSpecFlags добавляет в набор флагов --var и --include-root.
+func addSpecFlags(fs *flag.FlagSet) *specFlags {
+	f := &specFlags{vars: varFlag{}}
+	fs.Var(f.vars, "var", "значение переменной спецификации name=value (можно повторять)")
+	fs.StringVar(&f.includeRoot, "include-root", "",
+		"каталог, которым ограничены includes и $ref (по умолчанию каталог спецификации)")
+	return f
+}
+
+// options возвращает параметры разбора для заданных флагов.
+func (f *specFlags) options() []parser.Option {
+	opts := []parser.Option{parser.WithVariables(f.vars)}
+	if f.includeRoot != "" {
+		opts = append(opts, parser.WithIncludeRoot(f.includeRoot))
+	}
+	return opts
+}
+
+// writeJSON выводит v в виде отформатированного JSON.
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(v)
+}
+
+// loadSpec разбирает и проверяет файл спецификации. Предупреждения
+// и ошибки выводятся в stderr; при ошибках возвращается nil.
+func loadSpec(filename string, flags *specFlags, stderr io.Writer) *parser.OpenInfraSpec {
+	spec, err := parser.ParseFile(filename, flags.options()...)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return nil
+	}
+	for _, w := range spec.Warnings() {
+		fmt.Fprintf(stderr, "предупреждение: %s\n", w)
+	}
+	if errs := parser.Validate(spec); len(errs) > 0 {
+		fmt.Fprintln(stderr, errs)
+		return nil
+	}
+	return spec
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Ilya-Guyduk/openinfra/parser"
+)
+
+// runFmt приводит файлы к каноническому виду, см. parser.Format.
+// С --check файлы не меняются: выводятся имена неотформатированных
+// файлов, и команда завершается с кодом 1, если такие есть. Результат
+// всегда YAML, поэтому файлы .json с -w и --check не обрабатываются.
+func runFmt(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("fmt", stderr)
+	write := fs.Bool("w", false, "записать результат в исходные файлы")
+	check := fs.Bool("check", false, "только проверить, что файлы отформатированы")
+	files, err := parseArgs(fs, args)
+	if err != nil {
+		return exitUsage
+	}
+	if *write && *check {
+		fmt.Fprintln(stderr, "openinfra fmt: флаги -w и --check несовместимы")
+		return exitUsage
+	}
+	if len(files) == 0 {
+		files = []string{defaultSpecFile}
+	}
+
+	code := exitOK
+	for _, filename := range files {
+		if (*write || *check) && strings.EqualFold(filepath.Ext(filename), ".json") {
+			fmt.Fprintf(stderr, "openinfra fmt: %s: fmt записывает YAML и не меняет файлы JSON; "+
+				"чтобы перевести файл в YAML, выполните openinfra fmt %s > файл.yaml\n", filename, filename)
+			code = exitError
+			continue
+		}
+		original, formatted, err := formatFile(filename)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			code = exitError
+			continue
+		}
+
+		switch {
+		case *check:
+			if !bytes.Equal(original, formatted) {
+				fmt.Fprintln(stdout, filename)
+				code = exitError
+			}
+		case *write:
+			if bytes.Equal(original, formatted) {
+				continue
+			}
+			if err := rewriteFile(filename, formatted); err != nil {
+				fmt.Fprintln(stderr, err)
+				code = exitError
+			}
+		default:
+			stdout.Write(formatted)
+		}
+	}
+	return code
+}
+
+// formatFile возвращает исходное и отформатированное содержимое файла.
+func formatFile(filename string) ([]byte, []byte, error) {
+	original, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ошибка при чтении файла %s: %w", filename, err)
+	}
+	formatted, err := parser.Format(original, parser.WithSource(filename))
+	if err != nil {
+		return nil, nil, err
+	}
+	return original, formatted, nil
+}
+
+// rewriteFile заменяет содержимое существующего файла, сохраняя его права.
+func rewriteFile(filename string, data []byte) error {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return fmt.Errorf("ошибка при записи файла %s: %w", filename, err)
+	}
+	if err := os.WriteFile(filename, data, info.Mode().Perm()); err != nil {
+		return fmt.Errorf("ошибка при записи файла %s: %w", filename, err)
+	}
+	return nil
+}
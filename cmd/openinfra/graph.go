@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Ilya-Guyduk/openinfra/graph"
+)
+
+// graphNode — компонент графа для вывода в JSON.
+type graphNode struct {
+	Name      string   `json:"name"`
+	Wave      int      `json:"wave"`
+	DependsOn []string `json:"depends_on"`
+}
+
+// runGraph выводит граф зависимостей компонентов по волнам: компоненты
+// одной волны не зависят друг от друга и применяются параллельно.
+func runGraph(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("graph", stderr)
+	file := fs.String("f", defaultSpecFile, "файл спецификации")
+	flags := addSpecFlags(fs)
+	asJSON := fs.Bool("json", false, "вывести граф в JSON")
+	asDOT := fs.Bool("dot", false, "вывести граф в формате Graphviz DOT")
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return exitUsage
+	}
+	if len(rest) > 0 {
+		fmt.Fprintf(stderr, "openinfra graph: лишние аргументы: %v\n", rest)
+		return exitUsage
+	}
+
+	spec := loadSpec(*file, flags, stderr)
+	if spec == nil {
+		return exitError
+	}
+	g, err := graph.Build(spec)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+	waves, err := g.Waves()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+
+	switch {
+	case *asJSON:
+		nodes := []graphNode{}
+		for i, wave := range waves {
+			for _, name := range wave {
+				nodes = append(nodes, graphNode{
+					Name:      name,
+					Wave:      i + 1,
+					DependsOn: append([]string{}, g.DependenciesOf(name)...),
+				})
+			}
+		}
+		return jsonResult(writeJSON(stdout, nodes), stderr)
+	case *asDOT:
+		fmt.Fprintln(stdout, "digraph openinfra {")
+		for _, name := range g.Nodes() {
+			fmt.Fprintf(stdout, "  %s;\n", strconv.Quote(name))
+			for _, dep := range g.DependenciesOf(name) {
+				fmt.Fprintf(stdout, "  %s -> %s;\n", strconv.Quote(name), strconv.Quote(dep))
+			}
+		}
+		fmt.Fprintln(stdout, "}")
+	default:
+		tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "WAVE\tCOMPONENT\tDEPENDS ON")
+		for i, wave := range waves {
+			for _, name := range wave {
+				fmt.Fprintf(tw, "%d\t%s\t%s\n", i+1, name, strings.Join(g.DependenciesOf(name), ", "))
+			}
+		}
+		tw.Flush()
+	}
+	return exitOK
+}
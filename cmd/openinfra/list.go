@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Ilya-Guyduk/openinfra/graph"
+)
+
+// providerInfo — сведения о провайдере для вывода. Учётные данные
+// не выводятся, только метод аутентификации.
+type providerInfo struct {
+	Name         string   `json:"name"`
+	Type         string   `json:"type"`
+	Protocol     string   `json:"protocol,omitempty"`
+	Host         string   `json:"host,omitempty"`
+	Port         int      `json:"port,omitempty"`
+	Endpoint     string   `json:"endpoint,omitempty"`
+	Auth         string   `json:"auth,omitempty"`
+	Capabilities []string `json:"capabilities"`
+}
+
+// componentInfo — сведения о компоненте для вывода.
+type componentInfo struct {
+	Name       string                 `json:"name"`
+	Type       string                 `json:"type"`
+	Provider   string                 `json:"provider"`
+	Actions    []string               `json:"actions"`
+	DependsOn  []string               `json:"depends_on"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+}
+
+// runProviders выводит провайдеров спецификации и их возможности.
+func runProviders(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("providers", stderr)
+	file := fs.String("f", defaultSpecFile, "файл спецификации")
+	flags := addSpecFlags(fs)
+	asJSON := fs.Bool("json", false, "вывести список в JSON")
+	providerType := fs.String("type", "", "показать только провайдеров этого типа")
+	if _, err := parseArgs(fs, args); err != nil {
+		return exitUsage
+	}
+
+	spec := loadSpec(*file, flags, stderr)
+	if spec == nil {
+		return exitError
+	}
+
+	infos := []providerInfo{}
+	for _, p := range spec.GetProviderList() {
+		if *providerType != "" && p.Type != *providerType {
+			continue
+		}
+		info := providerInfo{
+			Name:         p.Name,
+			Type:         p.Type,
+			Protocol:     p.Connection.Protocol,
+			Host:         p.Connection.Host,
+			Port:         p.Connection.Port,
+			Endpoint:     p.Connection.Endpoint,
+			Auth:         p.Connection.Authentication.Method,
+			Capabilities: []string{},
+		}
+		for _, c := range p.Capabilities {
+			info.Capabilities = append(info.Capabilities, c.Name)
+		}
+		infos = append(infos, info)
+	}
+
+	if *asJSON {
+		return jsonResult(writeJSON(stdout, infos), stderr)
+	}
+	tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tTYPE\tENDPOINT\tAUTH\tCAPABILITIES")
+	for _, info := range infos {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", info.Name, info.Type,
+			providerAddress(info), info.Auth, strings.Join(info.Capabilities, ", "))
+	}
+	tw.Flush()
+	return exitOK
+}
+
+// providerAddress возвращает адрес провайдера для таблицы.
+func providerAddress(info providerInfo) string {
+	if info.Endpoint != "" {
+		return info.Endpoint
+	}
+	addr := info.Host
+	if info.Port != 0 {
+		addr = fmt.Sprintf("%s:%d", addr, info.Port)
+	}
+	if addr != "" && info.Protocol != "" {
+		addr = info.Protocol + "://" + addr
+	}
+	return addr
+}
+
+// runComponents выводит компоненты спецификации.
+func runComponents(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("components", stderr)
+	file := fs.String("f", defaultSpecFile, "файл спецификации")
+	flags := addSpecFlags(fs)
+	asJSON := fs.Bool("json", false, "вывести список в JSON")
+	provider := fs.String("provider", "", "показать только компоненты этого провайдера")
+	if _, err := parseArgs(fs, args); err != nil {
+		return exitUsage
+	}
+
+	spec := loadSpec(*file, flags, stderr)
+	if spec == nil {
+		return exitError
+	}
+
+	g, err := graph.Build(spec)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+
+	infos := []componentInfo{}
+	for _, r := range spec.GetResourceList() {
+		if *provider != "" && r.Provider != *provider {
+			continue
+		}
+		info := componentInfo{
+			Name:       r.Name,
+			Type:       r.Type,
+			Provider:   r.Provider,
+			Actions:    []string{},
+			DependsOn:  append([]string{}, g.DependenciesOf(r.Name)...),
+			Properties: r.Properties,
+		}
+		for _, a := range r.Actions {
+			info.Actions = append(info.Actions, a.Name)
+		}
+		infos = append(infos, info)
+	}
+
+	if *asJSON {
+		return jsonResult(writeJSON(stdout, infos), stderr)
+	}
+	tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tTYPE\tPROVIDER\tACTIONS\tDEPENDS ON")
+	for _, info := range infos {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", info.Name, info.Type, info.Provider,
+			strings.Join(info.Actions, ", "), strings.Join(info.DependsOn, ", "))
+	}
+	tw.Flush()
+	return exitOK
+}
+
+// jsonResult превращает ошибку вывода JSON в код завершения.
+func jsonResult(err error, stderr io.Writer) int {
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+	return exitOK
+}
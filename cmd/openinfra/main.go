@@ -0,0 +1,122 @@
+// Команда openinfra — инструмент командной строки для работы
+// со спецификациями OpenInfra.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// Коды завершения.
+const (
+	exitOK    = 0 // успешно
+	exitError = 1 // спецификация не прошла проверку или команда завершилась ошибкой
+	exitUsage = 2 // неверные аргументы командной строки
+)
+
+// command описывает подкоманду CLI.
+type command struct {
+	usage string
+	help  string
+	run   func(args []string, stdout, stderr io.Writer) int
+}
+
+var commands = map[string]command{
+	"validate": {
+		usage: "validate [--json] [--strict] [FILE...]",
+		help:  "проверить спецификации: ссылки, обязательные поля, циклы",
+		run:   runValidate,
+	},
+	"fmt": {
+		usage: "fmt [-w | --check] [FILE...]",
+		help:  "привести файлы к каноническому виду: порядок ключей, отступы, кавычки",
+		run:   runFmt,
+	},
+	"graph": {
+		usage: "graph [-f FILE] [--json | --dot]",
+		help:  "вывести граф зависимостей компонентов по волнам",
+		run:   runGraph,
+	},
+	"providers": {
+		usage: "providers [-f FILE] [--json] [--type TYPE]",
+		help:  "вывести провайдеров и их возможности",
+		run:   runProviders,
+	},
+	"components": {
+		usage: "components [-f FILE] [--json] [--provider NAME]",
+		help:  "вывести компоненты",
+		run:   runComponents,
+	},
+	"exec": {
+		usage: "exec [-f FILE] [--json] PROVIDER CAPABILITY [--param k=v]...",
+		help:  "вызвать возможность провайдера",
+		run:   runExec,
+	},
+	"apply": {
+		usage: "apply [-f FILE] [--json] [--concurrency N] ACTION",
+		help:  "выполнить действие для всех компонентов с учётом зависимостей",
+		run:   runApply,
+	},
+	"merge": {
+		usage: "merge [-o FILE] BASE [OVERLAY...]",
+		help:  "наложить файлы окружений на базовую спецификацию и вывести результат",
+		run:   runMerge,
+	},
+	"migrate": {
+		usage: "migrate [-w] FILE...",
+		help:  "переписать документы в форму текущей версии формата",
+		run:   runMigrate,
+	},
+	"schema": {
+		usage: "schema [-o FILE] | schema validate FILE...",
+		help:  "вывести JSON Schema формата или проверить документы по ней",
+		run:   runSchema,
+	},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run выполняет команду и возвращает код завершения.
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return exitUsage
+	}
+
+	switch args[0] {
+	case "help", "-h", "-help", "--help":
+		usage(stdout)
+		return exitOK
+	}
+
+	cmd, exists := commands[args[0]]
+	if !exists {
+		fmt.Fprintf(stderr, "openinfra: неизвестная команда %q\n\n", args[0])
+		usage(stderr)
+		return exitUsage
+	}
+	return cmd.run(args[1:], stdout, stderr)
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Использование: openinfra <команда> [аргументы]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Команды:")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %s\n      %s\n", commands[name].usage, commands[name].help)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "Файл спецификации по умолчанию: %s\n", defaultSpecFile)
+	fmt.Fprintln(w, "Команды, которые разбирают спецификацию, принимают --var name=value для выражений ${var.name}")
+	fmt.Fprintln(w, "Коды завершения: 0 — успешно, 1 — ошибка в спецификации или при выполнении, 2 — неверные аргументы")
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Ilya-Guyduk/openinfra/parser"
+)
+
+// runCLI запускает команду и возвращает код завершения и вывод.
+func runCLI(args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+// writeFile создаёт во временном каталоге файл с содержимым content.
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestUsage(t *testing.T) {
+	code, _, stderr := runCLI()
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, stderr, "Использование: openinfra")
+
+	code, _, stderr = runCLI("nope")
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, stderr, `неизвестная команда "nope"`)
+
+	code, stdout, _ := runCLI("help")
+	assert.Equal(t, exitOK, code)
+	assert.Contains(t, stdout, "schema")
+}
+
+func TestSchema(t *testing.T) {
+	want, err := parser.JSONSchemaBytes()
+	require.NoError(t, err)
+
+	code, stdout, _ := runCLI("schema")
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, string(want), stdout)
+
+	out := filepath.Join(t.TempDir(), "schema.json")
+	code, _, _ = runCLI("schema", "-o", out)
+	assert.Equal(t, exitOK, code)
+	got, err := os.ReadFile(out)
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+}
+
+func TestSchemaValidate(t *testing.T) {
+	good := writeFile(t, "good.yaml", "openinfra: 1.0.0\nproviders:\n  - name: p\n    type: t\n")
+	bad := writeFile(t, "bad.yaml", "openinfra: 1.0.0\nprovider: []\n")
+
+	code, stdout, _ := runCLI("schema", "validate", good)
+	assert.Equal(t, exitOK, code)
+	assert.Empty(t, stdout)
+
+	code, stdout, _ = runCLI("schema", "validate", good, bad)
+	assert.Equal(t, exitError, code)
+	assert.Contains(t, stdout, bad+":2:1: неизвестное поле provider")
+
+	code, _, _ = runCLI("schema", "validate")
+	assert.Equal(t, exitUsage, code)
+}
+
+const legacySpec = `openinfra: 1.0.0
+providers:
+  # основной гипервизор
+  - name: vbox
+    type: hypervisor
+    connection_details:
+      address: 192.168.1.10
+      username: admin
+`
+
+func TestMigrate(t *testing.T) {
+	path := writeFile(t, "legacy.yaml", legacySpec)
+
+	code, stdout, stderr := runCLI("migrate", path)
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, `openinfra: 1.1.0
+providers:
+  # основной гипервизор
+  - name: vbox
+    type: hypervisor
+    connection:
+      host: 192.168.1.10
+      authentication:
+        method: password
+        username: admin
+`, stdout)
+	assert.Contains(t, stderr, path+":6:5: connection_details устарел")
+
+	// Без -w файл не меняется
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, legacySpec, string(data))
+
+	require.NoError(t, os.Chmod(path, 0o600))
+	code, _, _ = runCLI("migrate", "-w", path)
+	assert.Equal(t, exitOK, code)
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, stdout, string(data))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	spec, err := parser.ParseFile(path)
+	require.NoError(t, err)
+	assert.Empty(t, spec.Warnings())
+}
+
+func TestMigrateErrors(t *testing.T) {
+	code, _, _ := runCLI("migrate")
+	assert.Equal(t, exitUsage, code)
+
+	path := writeFile(t, "future.yaml", "openinfra: 2.0.0\n")
+	code, _, stderr := runCLI("migrate", "-w", path)
+	assert.Equal(t, exitError, code)
+	assert.Contains(t, stderr, "версия 2.0.0 не поддерживается")
+
+	// Файл в актуальной форме не переписывается, даже если разметка другая
+	current := "openinfra: 1.1.0\nproviders: [{name: p, type: t}]\n"
+	path = writeFile(t, "current.yaml", current)
+	code, _, _ = runCLI("migrate", "-w", path)
+	assert.Equal(t, exitOK, code)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, current, string(data))
+}
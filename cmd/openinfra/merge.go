@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Ilya-Guyduk/openinfra/parser"
+)
+
+// runMerge накладывает файлы окружения на базовую спецификацию и выводит
+// итоговый документ, см. parser.Merge. Итоговая спецификация проверяется;
+// ошибки указывают на файл, из которого взято значение.
+func runMerge(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("merge", stderr)
+	output := fs.String("o", "", "записать результат в файл вместо стандартного вывода")
+	files, err := parseArgs(fs, args)
+	if err != nil {
+		return exitUsage
+	}
+	if len(files) == 0 {
+		fmt.Fprintln(stderr, "openinfra merge: не указан базовый файл")
+		return exitUsage
+	}
+
+	doc, err := parser.MergeFiles(files[0], files[1:]...)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+	spec, err := doc.Spec()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+	for _, w := range spec.Warnings() {
+		fmt.Fprintf(stderr, "предупреждение: %s\n", w)
+	}
+	if errs := parser.Validate(spec); len(errs) > 0 {
+		fmt.Fprintln(stderr, errs)
+		return exitError
+	}
+
+	data, err := doc.Bytes()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+	if *output == "" {
+		stdout.Write(data)
+		return exitOK
+	}
+	if err := os.WriteFile(*output, data, 0o644); err != nil {
+		fmt.Fprintf(stderr, "ошибка при записи файла %s: %v\n", *output, err)
+		return exitError
+	}
+	return exitOK
+}
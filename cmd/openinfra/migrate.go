@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Ilya-Guyduk/openinfra/parser"
+)
+
+// runMigrate переписывает документы в форму текущей версии формата,
+// сохраняя комментарии и порядок ключей.
+func runMigrate(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	write := fs.Bool("w", false, "записать результат в исходные файлы")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() == 0 {
+		fmt.Fprintln(stderr, "openinfra migrate: не указаны файлы")
+		return exitUsage
+	}
+
+	code := exitOK
+	for _, filename := range fs.Args() {
+		if err := migrateFile(filename, *write, stdout, stderr); err != nil {
+			fmt.Fprintln(stderr, err)
+			code = exitError
+		}
+	}
+	return code
+}
+
+func migrateFile(filename string, write bool, stdout, stderr io.Writer) error {
+	original, err := os.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("ошибка при чтении файла %s: %w", filename, err)
+	}
+	doc, err := parser.ParseDocument(original, parser.WithSource(filename))
+	if err != nil {
+		return err
+	}
+
+	// Сравнение с закодированным исходным деревом, а не с файлом, чтобы
+	// не переписывать файлы, которым нужна только другая разметка
+	before, err := doc.Bytes()
+	if err != nil {
+		return err
+	}
+
+	warnings, err := doc.Migrate()
+	if err != nil {
+		return err
+	}
+	for _, w := range warnings {
+		fmt.Fprintln(stderr, w)
+	}
+
+	data, err := doc.Bytes()
+	if err != nil {
+		return err
+	}
+	if !write {
+		_, err := stdout.Write(data)
+		return err
+	}
+	if bytes.Equal(data, before) {
+		return nil
+	}
+	return rewriteFile(filename, data)
+}
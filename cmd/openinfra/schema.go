@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Ilya-Guyduk/openinfra/parser"
+)
+
+// runSchema выводит JSON Schema формата или, с подкомандой validate,
+// проверяет по ней документы.
+func runSchema(args []string, stdout, stderr io.Writer) int {
+	if len(args) > 0 && args[0] == "validate" {
+		return runSchemaValidate(args[1:], stdout, stderr)
+	}
+
+	fs := flag.NewFlagSet("schema", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	output := fs.String("o", "", "записать схему в файл вместо стандартного вывода")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(stderr, "openinfra schema: лишние аргументы: %v\n", fs.Args())
+		return exitUsage
+	}
+
+	data, err := parser.JSONSchemaBytes()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+
+	if *output == "" {
+		stdout.Write(data)
+		return exitOK
+	}
+	if err := os.WriteFile(*output, data, 0o644); err != nil {
+		fmt.Fprintf(stderr, "ошибка при записи файла %s: %v\n", *output, err)
+		return exitError
+	}
+	return exitOK
+}
+
+func runSchemaValidate(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprintln(stderr, "openinfra schema validate: не указаны файлы")
+		return exitUsage
+	}
+
+	code := exitOK
+	for _, filename := range args {
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			fmt.Fprintf(stderr, "ошибка при чтении файла %s: %v\n", filename, err)
+			code = exitError
+			continue
+		}
+
+		errs, err := parser.ValidateSchema(data, parser.WithSource(filename), parser.WithIncludeFiles())
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			code = exitError
+			continue
+		}
+		if len(errs) > 0 {
+			fmt.Fprintln(stdout, errs)
+			code = exitError
+		}
+	}
+	return code
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/Ilya-Guyduk/openinfra/graph"
+	"github.com/Ilya-Guyduk/openinfra/parser"
+)
+
+// Коды ошибок, которые добавляет validate к ошибкам parser.Validate.
+const (
+	codeParse           parser.ErrorCode = "parse"
+	codeDependencyCycle parser.ErrorCode = "dependency_cycle"
+)
+
+// fileReport — результат проверки одного файла для вывода в JSON.
+type fileReport struct {
+	File     string                    `json:"file"`
+	Valid    bool                      `json:"valid"`
+	Errors   []*parser.ValidationError `json:"errors"`
+	Warnings []*parser.ValidationError `json:"warnings"`
+}
+
+// runValidate разбирает файлы, проверяет ссылки, обязательные поля
+// и граф зависимостей.
+func runValidate(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("validate", stderr)
+	asJSON := fs.Bool("json", false, "вывести результат в JSON")
+	strict := fs.Bool("strict", false, "считать ошибкой неизвестные и опечатанные ключи")
+	flags := addSpecFlags(fs)
+	files, err := parseArgs(fs, args)
+	if err != nil {
+		return exitUsage
+	}
+	if len(files) == 0 {
+		files = []string{defaultSpecFile}
+	}
+
+	code := exitOK
+	reports := make([]fileReport, 0, len(files))
+	for _, filename := range files {
+		// В режиме восстановления сообщаются все элементы, которые
+		// не удалось разобрать, а не только первый
+		opts := append(flags.options(), parser.WithRecovery())
+		if *strict {
+			opts = append(opts, parser.WithStrict())
+		}
+		report := validateFile(filename, opts...)
+		if !report.Valid {
+			code = exitError
+		}
+		reports = append(reports, report)
+	}
+
+	if *asJSON {
+		if jsonResult(writeJSON(stdout, reports), stderr) != exitOK {
+			return exitError
+		}
+		return code
+	}
+
+	for _, report := range reports {
+		for _, w := range report.Warnings {
+			fmt.Fprintf(stderr, "предупреждение: %s\n", w)
+		}
+		for _, e := range report.Errors {
+			// Ошибки разбора сами называют файл, остальным без позиции
+			// имя файла добавляется явно
+			if !e.IsValid() && e.Code != codeParse {
+				fmt.Fprintf(stdout, "%s: %s\n", report.File, e)
+				continue
+			}
+			fmt.Fprintln(stdout, e)
+		}
+		if report.Valid {
+			fmt.Fprintf(stdout, "%s: ok\n", report.File)
+		}
+	}
+	return code
+}
+
+func validateFile(filename string, opts ...parser.Option) fileReport {
+	report := fileReport{
+		File:     filename,
+		Errors:   []*parser.ValidationError{},
+		Warnings: []*parser.ValidationError{},
+	}
+
+	// Файл может содержать поток документов: проверяется каждый
+	specs, err := parser.ParseAllFile(filename, opts...)
+	var unknown parser.ValidationErrors
+	if errors.As(err, &unknown) {
+		// Строгий режим и режим восстановления возвращают ошибки с позициями
+		// вместе с частичными спецификациями: их проверка продолжается
+		report.Errors = append(report.Errors, unknown...)
+		err = nil
+	}
+	if err != nil {
+		// Ошибка разбора не привязана к узлу: позиция остаётся только в тексте
+		report.Errors = append(report.Errors, &parser.ValidationError{
+			Code:     codeParse,
+			Message:  err.Error(),
+			Position: parser.Position{File: filename},
+		})
+		return report
+	}
+
+	for i, spec := range specs {
+		report.Warnings = append(report.Warnings, spec.Warnings()...)
+		errs := parser.Validate(spec)
+		report.Errors = append(report.Errors, errs...)
+		if len(errs) > 0 {
+			continue
+		}
+		// Граф проверяется только для корректных ссылок, иначе ошибки дублируются
+		g, err := graph.Build(spec)
+		if err != nil && len(unknown) > 0 {
+			// Ссылка на элемент, пропущенный при разборе: об ошибке уже сообщено
+			continue
+		}
+		if err == nil {
+			_, err = g.TopologicalOrder()
+		}
+		if err != nil {
+			if len(specs) > 1 {
+				err = &parser.DocumentError{Index: i, Err: err}
+			}
+			report.Errors = append(report.Errors, &parser.ValidationError{
+				Code:     codeDependencyCycle,
+				Message:  err.Error(),
+				Position: parser.Position{File: filename},
+			})
+		}
+	}
+	report.Valid = len(report.Errors) == 0
+	return report
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"log"
 
 	"github.com/Ilya-Guyduk/openinfra/parser"
 )
@@ -9,13 +10,13 @@ import (
 func main() {
 	spec, err := parser.ParseFile("./some_file.yaml")
 	if err != nil {
-		fmt.Errorf("Error: %s", err)
+		log.Fatalf("Error: %s", err)
 	}
 
 	providerList := spec.GetProviderList()
 
 	for _, provider := range providerList {
-		fmt.Println(provider)
+		fmt.Println(provider.Name, provider.Type)
 	}
 
 }
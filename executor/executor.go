@@ -0,0 +1,284 @@
+// Package executor выполняет действия компонентов OpenInfra через
+// возможности их провайдеров с учётом зависимостей между компонентами.
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Ilya-Guyduk/openinfra/graph"
+	"github.com/Ilya-Guyduk/openinfra/parser"
+)
+
+// Status — итог обработки одного компонента.
+type Status string
+
+const (
+	// StatusSucceeded — действие выполнено успешно
+	StatusSucceeded Status = "succeeded"
+	// StatusFailed — действие завершилось ошибкой
+	StatusFailed Status = "failed"
+	// StatusSkipped — действие не запускалось, потому что не удалась зависимость
+	StatusSkipped Status = "skipped"
+	// StatusNoop — у компонента нет такого действия
+	StatusNoop Status = "noop"
+	// StatusCanceled — выполнение прервано через контекст
+	StatusCanceled Status = "canceled"
+)
+
+// DefaultConcurrency — число одновременно выполняемых действий по умолчанию.
+const DefaultConcurrency = 4
+
+// Result описывает выполнение действия для одного компонента.
+type Result struct {
+	Component  string        `json:"component"`
+	Provider   string        `json:"provider,omitempty"`
+	Action     string        `json:"action"`
+	Capability string        `json:"capability,omitempty"`
+	Status     Status        `json:"status"`
+	StatusCode int           `json:"status_code,omitempty"`
+	Output     string        `json:"output,omitempty"`
+	Err        error         `json:"-"`
+	Duration   time.Duration `json:"duration"`
+	// BlockedBy — неудавшаяся зависимость, из-за которой компонент пропущен
+	BlockedBy string `json:"blocked_by,omitempty"`
+}
+
+// ok сообщает, можно ли запускать компоненты, зависящие от этого.
+func (r *Result) ok() bool {
+	return r.Status == StatusSucceeded || r.Status == StatusNoop
+}
+
+// Report — результаты выполнения действия по всем компонентам
+// в топологическом порядке.
+type Report struct {
+	Action  string   `json:"action"`
+	Results []Result `json:"results"`
+}
+
+// Result возвращает результат для компонента name.
+func (r *Report) Result(name string) (Result, bool) {
+	for _, res := range r.Results {
+		if res.Component == name {
+			return res, true
+		}
+	}
+	return Result{}, false
+}
+
+// Failed сообщает, был ли хотя бы один компонент не выполнен.
+func (r *Report) Failed() bool {
+	for i := range r.Results {
+		if !r.Results[i].ok() {
+			return true
+		}
+	}
+	return false
+}
+
+// Err объединяет ошибки неудавшихся компонентов или возвращает nil.
+func (r *Report) Err() error {
+	var msgs []string
+	for _, res := range r.Results {
+		if res.Status == StatusFailed {
+			msgs = append(msgs, fmt.Sprintf("%s: %v", res.Component, res.Err))
+		}
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(msgs, "; "))
+}
+
+// Executor применяет действия к компонентам спецификации.
+type Executor struct {
+	spec        *parser.OpenInfraSpec
+	graph       *graph.Graph
+	order       []string
+	concurrency int
+	execOpts    []parser.ExecOption
+}
+
+// Option настраивает Executor.
+type Option func(*Executor)
+
+// WithConcurrency ограничивает число одновременно выполняемых действий.
+func WithConcurrency(n int) Option {
+	return func(e *Executor) {
+		if n > 0 {
+			e.concurrency = n
+		}
+	}
+}
+
+// WithExecOptions передаёт опции выполнения (HTTP-клиент, таймауты)
+// в каждый вызов возможности провайдера.
+func WithExecOptions(opts ...parser.ExecOption) Option {
+	return func(e *Executor) {
+		e.execOpts = append(e.execOpts, opts...)
+	}
+}
+
+// New создаёт исполнитель для спецификации. Возвращает ошибку, если граф
+// зависимостей содержит неизвестные компоненты или циклы.
+func New(spec *parser.OpenInfraSpec, opts ...Option) (*Executor, error) {
+	g, err := graph.Build(spec)
+	if err != nil {
+		return nil, err
+	}
+	order, err := g.TopologicalOrder()
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Executor{spec: spec, graph: g, order: order, concurrency: DefaultConcurrency}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e, nil
+}
+
+// Run выполняет действие action для всех компонентов, у которых оно
+// объявлено. Компонент запускается после успешного завершения всех его
+// зависимостей; если зависимость не удалась, компонент и все зависящие
+// от него пропускаются, а независимые ветви продолжают выполняться.
+func (e *Executor) Run(ctx context.Context, action string) (*Report, error) {
+	results := make(map[string]*Result, len(e.order))
+	remaining := make(map[string]int, len(e.order))
+	var queue []string
+	for _, name := range e.order {
+		remaining[name] = len(e.graph.DependenciesOf(name))
+		if remaining[name] == 0 {
+			queue = append(queue, name)
+		}
+	}
+
+	done := make(chan *Result)
+	running := 0
+
+	// finish фиксирует результат и ставит в очередь компоненты,
+	// у которых завершились все зависимости.
+	finish := func(res *Result) {
+		results[res.Component] = res
+		for _, d := range e.graph.DependentsOf(res.Component) {
+			remaining[d]--
+			if remaining[d] == 0 {
+				queue = append(queue, d)
+			}
+		}
+	}
+
+	for len(results) < len(e.order) {
+		for len(queue) > 0 && running < e.concurrency {
+			name := queue[0]
+			queue = queue[1:]
+
+			if blocker := e.blockedBy(name, results); blocker != "" {
+				finish(&Result{Component: name, Action: action, Status: StatusSkipped, BlockedBy: blocker})
+				continue
+			}
+			if err := ctx.Err(); err != nil {
+				finish(&Result{Component: name, Action: action, Status: StatusCanceled, Err: err})
+				continue
+			}
+
+			running++
+			go func(name string) {
+				done <- e.runComponent(ctx, name, action)
+			}(name)
+		}
+
+		if running == 0 {
+			break
+		}
+		res := <-done
+		running--
+		finish(res)
+	}
+
+	report := &Report{Action: action, Results: make([]Result, 0, len(e.order))}
+	for _, name := range e.order {
+		if res, exists := results[name]; exists {
+			report.Results = append(report.Results, *res)
+		}
+	}
+	return report, ctx.Err()
+}
+
+// blockedBy возвращает первую зависимость компонента, которая не выполнена.
+func (e *Executor) blockedBy(name string, results map[string]*Result) string {
+	for _, d := range e.graph.DependenciesOf(name) {
+		if res := results[d]; res == nil || !res.ok() {
+			return d
+		}
+	}
+	return ""
+}
+
+// runComponent выполняет действие для одного компонента.
+func (e *Executor) runComponent(ctx context.Context, name, action string) *Result {
+	r := e.spec.Resources[name]
+	res := &Result{Component: name, Provider: r.Provider, Action: action}
+
+	act, exists := findAction(r, action)
+	if !exists {
+		res.Status = StatusNoop
+		return res
+	}
+	res.Capability = act.Capability
+	if res.Capability == "" {
+		res.Capability = act.Name
+	}
+
+	fail := func(err error) *Result {
+		res.Status = StatusFailed
+		res.Err = err
+		return res
+	}
+
+	provider, err := e.spec.GetProviderByName(r.Provider)
+	if err != nil {
+		return fail(r.Pos.Errorf("провайдер %s не найден", r.Provider))
+	}
+	if _, err := e.spec.GetProviderCapability(r.Provider, res.Capability); err != nil {
+		return fail(act.Pos.Errorf("%w", err))
+	}
+
+	start := time.Now()
+	result, err := provider.ExecuteCapabilityContext(ctx, res.Capability, Params(r), e.execOpts...)
+	res.Duration = time.Since(start)
+	if result != nil {
+		res.StatusCode = result.StatusCode
+		res.Output = result.String()
+	}
+	if err != nil {
+		return fail(err)
+	}
+	res.Status = StatusSucceeded
+	return res
+}
+
+// Params возвращает параметры для возможности провайдера: свойства
+// компонента и его имя под ключом name, если такого свойства нет.
+func Params(r parser.Resource) map[string]interface{} {
+	params := make(map[string]interface{}, len(r.Properties)+1)
+	for k, v := range r.Properties {
+		params[k] = v
+	}
+	if _, exists := params["name"]; !exists {
+		params["name"] = r.Name
+	}
+	return params
+}
+
+func findAction(r parser.Resource, name string) (parser.Action, bool) {
+	for _, a := range r.Actions {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return parser.Action{}, false
+}
@@ -0,0 +1,161 @@
+package executor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/Ilya-Guyduk/openinfra/parser"
+	"github.com/stretchr/testify/assert"
+)
+
+// recorder запоминает порядок запросов к тестовому провайдеру.
+type recorder struct {
+	mu    sync.Mutex
+	paths []string
+}
+
+func (rec *recorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rec.mu.Lock()
+	rec.paths = append(rec.paths, r.URL.Path)
+	rec.mu.Unlock()
+	w.Write([]byte("ok " + r.URL.Path))
+}
+
+func testSpec(url string) *parser.OpenInfraSpec {
+	start := []parser.Action{{Name: "start", Capability: "start_vm"}}
+	return &parser.OpenInfraSpec{
+		Providers: map[string]parser.Provider{
+			"vbox": {
+				Name:       "vbox",
+				Connection: parser.Connection{Endpoint: url},
+				Capabilities: []parser.Capability{
+					{Name: "start_vm", Method: "POST", Endpoint: "/vms/{name}/start",
+						Parameters: []parser.Parameter{{Name: "name", Required: true}}},
+				},
+			},
+		},
+		Resources: map[string]parser.Resource{
+			"net":    {Name: "net", Provider: "vbox", Actions: start},
+			"vm":     {Name: "vm", Provider: "vbox", Actions: start},
+			"app":    {Name: "app", Provider: "vbox", Actions: start},
+			"db":     {Name: "db", Provider: "vbox", Actions: start},
+			"static": {Name: "static", Provider: "vbox"},
+		},
+		Dependencies: []parser.Dependency{
+			{Resource: "vm", DependsOn: []string{"net"}},
+			{Resource: "app", DependsOn: []string{"vm", "static"}},
+		},
+	}
+}
+
+func TestRunRespectsDependencies(t *testing.T) {
+	rec := &recorder{}
+	ts := httptest.NewServer(rec)
+	defer ts.Close()
+
+	exec, err := New(testSpec(ts.URL), WithConcurrency(1))
+	assert.NoError(t, err)
+
+	report, err := exec.Run(context.Background(), "start")
+	assert.NoError(t, err)
+	assert.False(t, report.Failed())
+	assert.NoError(t, report.Err())
+
+	assert.Equal(t, []string{"/vms/db/start", "/vms/net/start", "/vms/vm/start", "/vms/app/start"}, rec.paths)
+
+	res, ok := report.Result("static")
+	assert.True(t, ok)
+	assert.Equal(t, StatusNoop, res.Status)
+
+	res, _ = report.Result("app")
+	assert.Equal(t, StatusSucceeded, res.Status)
+	assert.Equal(t, "start_vm", res.Capability)
+	assert.Equal(t, "ok /vms/app/start", res.Output)
+}
+
+func TestRunFailureSkipsDependents(t *testing.T) {
+	rec := &recorder{}
+	ts := httptest.NewServer(rec)
+	defer ts.Close()
+
+	spec := testSpec(ts.URL)
+	net := spec.Resources["net"]
+	net.Actions = []parser.Action{{Name: "start", Capability: "missing"}}
+	spec.Resources["net"] = net
+
+	exec, err := New(spec)
+	assert.NoError(t, err)
+
+	report, err := exec.Run(context.Background(), "start")
+	assert.NoError(t, err)
+	assert.True(t, report.Failed())
+
+	statuses := make(map[string]Status)
+	for _, res := range report.Results {
+		statuses[res.Component] = res.Status
+	}
+	assert.Equal(t, map[string]Status{
+		"net":    StatusFailed,
+		"vm":     StatusSkipped,
+		"app":    StatusSkipped,
+		"db":     StatusSucceeded,
+		"static": StatusNoop,
+	}, statuses)
+
+	res, _ := report.Result("app")
+	assert.Equal(t, "vm", res.BlockedBy)
+	assert.EqualError(t, report.Err(), "net: capability missing not found for provider vbox")
+	assert.Equal(t, []string{"/vms/db/start"}, rec.paths)
+}
+
+func TestRunErrorPosition(t *testing.T) {
+	spec := testSpec("http://127.0.0.1:0")
+	spec.Resources = map[string]parser.Resource{
+		"net": {Name: "net", Provider: "vbox", Actions: []parser.Action{
+			{Name: "start", Capability: "missing", Pos: parser.Position{File: "spec.yaml", Line: 20, Column: 9}},
+		}},
+		"vm": {Name: "vm", Provider: "kvm", Actions: []parser.Action{{Name: "start"}},
+			Pos: parser.Position{File: "spec.yaml", Line: 24, Column: 5}},
+	}
+	spec.Dependencies = nil
+
+	exec, err := New(spec)
+	assert.NoError(t, err)
+	report, err := exec.Run(context.Background(), "start")
+	assert.NoError(t, err)
+
+	res, _ := report.Result("net")
+	assert.EqualError(t, res.Err, "spec.yaml:20:9: capability missing not found for provider vbox")
+	res, _ = report.Result("vm")
+	assert.EqualError(t, res.Err, "spec.yaml:24:5: провайдер kvm не найден")
+}
+
+func TestRunCanceled(t *testing.T) {
+	exec, err := New(testSpec("http://127.0.0.1:0"))
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	report, err := exec.Run(ctx, "start")
+	assert.ErrorIs(t, err, context.Canceled)
+	for _, res := range report.Results {
+		assert.Contains(t, []Status{StatusCanceled, StatusSkipped}, res.Status)
+	}
+}
+
+func TestNewRejectsCycles(t *testing.T) {
+	spec := &parser.OpenInfraSpec{
+		Resources: map[string]parser.Resource{"a": {Name: "a"}, "b": {Name: "b"}},
+		Dependencies: []parser.Dependency{
+			{Resource: "a", DependsOn: []string{"b"}},
+			{Resource: "b", DependsOn: []string{"a"}},
+		},
+	}
+
+	_, err := New(spec)
+	assert.EqualError(t, err, "обнаружен цикл зависимостей: a -> b -> a")
+}
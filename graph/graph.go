@@ -0,0 +1,227 @@
+// Package graph строит граф зависимостей компонентов OpenInfra и определяет
+// порядок, в котором их можно обрабатывать.
+package graph
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Ilya-Guyduk/openinfra/parser"
+)
+
+// Graph — ориентированный граф зависимостей между компонентами.
+// Ребро A → B означает, что A зависит от B и B нужно обработать раньше.
+type Graph struct {
+	nodes      []string
+	deps       map[string][]string
+	dependents map[string][]string
+}
+
+// CycleError сообщает о цикле в зависимостях.
+type CycleError struct {
+	// Path — компоненты цикла; первый элемент повторяется в конце
+	Path []string
+}
+
+func (e *CycleError) Error() string {
+	return fmt.Sprintf("обнаружен цикл зависимостей: %s", strings.Join(e.Path, " -> "))
+}
+
+// New создаёт пустой граф с узлами nodes.
+func New(nodes ...string) *Graph {
+	g := &Graph{
+		deps:       make(map[string][]string),
+		dependents: make(map[string][]string),
+	}
+	for _, n := range nodes {
+		g.AddNode(n)
+	}
+	return g
+}
+
+// Build строит граф по спецификации: узлы — компоненты, рёбра — зависимости
+// из корневого раздела dependencies и из dependencies самих компонентов.
+func Build(spec *parser.OpenInfraSpec) (*Graph, error) {
+	g := New()
+	for name := range spec.Resources {
+		g.AddNode(name)
+	}
+
+	if err := g.addDependencies(spec.Dependencies, ""); err != nil {
+		return nil, err
+	}
+	for name, r := range spec.Resources {
+		if err := g.addDependencies(r.Dependencies, name); err != nil {
+			return nil, err
+		}
+	}
+	return g, nil
+}
+
+// addDependencies добавляет рёбра из списка зависимостей. owner — компонент,
+// в котором объявлен список; он используется, если поле component пусто.
+func (g *Graph) addDependencies(deps []parser.Dependency, owner string) error {
+	for _, dep := range deps {
+		from := dep.Resource
+		if from == "" {
+			from = owner
+		}
+		if !g.HasNode(from) {
+			return dep.Pos.Errorf("зависимость объявлена для несуществующего компонента %q", from)
+		}
+		for _, to := range dep.DependsOn {
+			if !g.HasNode(to) {
+				return dep.Pos.Errorf("компонент %q зависит от несуществующего компонента %q", from, to)
+			}
+			g.AddEdge(from, to)
+		}
+	}
+	return nil
+}
+
+// AddNode добавляет узел, если его ещё нет.
+func (g *Graph) AddNode(name string) {
+	if g.HasNode(name) {
+		return
+	}
+	i := sort.SearchStrings(g.nodes, name)
+	g.nodes = append(g.nodes, "")
+	copy(g.nodes[i+1:], g.nodes[i:])
+	g.nodes[i] = name
+	g.deps[name] = nil
+}
+
+// AddEdge добавляет зависимость from от to; недостающие узлы создаются.
+func (g *Graph) AddEdge(from, to string) {
+	g.AddNode(from)
+	g.AddNode(to)
+	g.deps[from] = insertSorted(g.deps[from], to)
+	g.dependents[to] = insertSorted(g.dependents[to], from)
+}
+
+// HasNode сообщает, есть ли узел в графе.
+func (g *Graph) HasNode(name string) bool {
+	_, exists := g.deps[name]
+	return exists
+}
+
+// Nodes возвращает все узлы в алфавитном порядке.
+func (g *Graph) Nodes() []string {
+	return append([]string(nil), g.nodes...)
+}
+
+// DependenciesOf возвращает компоненты, от которых непосредственно зависит name.
+func (g *Graph) DependenciesOf(name string) []string {
+	return append([]string(nil), g.deps[name]...)
+}
+
+// DependentsOf возвращает компоненты, которые непосредственно зависят от name.
+func (g *Graph) DependentsOf(name string) []string {
+	return append([]string(nil), g.dependents[name]...)
+}
+
+// TopologicalOrder возвращает узлы в порядке, в котором зависимости идут
+// раньше зависящих от них компонентов. Порядок детерминирован: узлы одной
+// волны упорядочены по алфавиту.
+func (g *Graph) TopologicalOrder() ([]string, error) {
+	waves, err := g.Waves()
+	if err != nil {
+		return nil, err
+	}
+	order := make([]string, 0, len(g.nodes))
+	for _, wave := range waves {
+		order = append(order, wave...)
+	}
+	return order, nil
+}
+
+// Waves разбивает узлы на волны: все зависимости узла находятся в более
+// ранних волнах, поэтому узлы одной волны можно обрабатывать параллельно.
+func (g *Graph) Waves() ([][]string, error) {
+	if cycle := g.FindCycle(); cycle != nil {
+		return nil, &CycleError{Path: cycle}
+	}
+
+	remaining := make(map[string]int, len(g.nodes))
+	var wave []string
+	for _, n := range g.nodes {
+		remaining[n] = len(g.deps[n])
+		if remaining[n] == 0 {
+			wave = append(wave, n)
+		}
+	}
+
+	var waves [][]string
+	for len(wave) > 0 {
+		waves = append(waves, wave)
+		var next []string
+		for _, n := range wave {
+			for _, d := range g.dependents[n] {
+				remaining[d]--
+				if remaining[d] == 0 {
+					next = append(next, d)
+				}
+			}
+		}
+		sort.Strings(next)
+		wave = next
+	}
+	return waves, nil
+}
+
+// FindCycle возвращает первый найденный цикл (первый узел повторяется
+// в конце) или nil, если граф ацикличен.
+func (g *Graph) FindCycle() []string {
+	const (
+		unvisited = iota
+		inProgress
+		done
+	)
+	state := make(map[string]int, len(g.nodes))
+	var stack []string
+
+	var visit func(n string) []string
+	visit = func(n string) []string {
+		state[n] = inProgress
+		stack = append(stack, n)
+		for _, d := range g.deps[n] {
+			switch state[d] {
+			case inProgress:
+				for i, s := range stack {
+					if s == d {
+						return append(append([]string(nil), stack[i:]...), d)
+					}
+				}
+			case unvisited:
+				if cycle := visit(d); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[n] = done
+		return nil
+	}
+
+	for _, n := range g.nodes {
+		if state[n] == unvisited {
+			if cycle := visit(n); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
+
+// insertSorted вставляет s в отсортированный срез, избегая повторов.
+func insertSorted(list []string, s string) []string {
+	i := sort.SearchStrings(list, s)
+	if i < len(list) && list[i] == s {
+		return list
+	}
+	list = append(list, "")
+	copy(list[i+1:], list[i:])
+	list[i] = s
+	return list
+}
@@ -0,0 +1,78 @@
+package graph
+
+import (
+	"testing"
+
+	"github.com/Ilya-Guyduk/openinfra/parser"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuild(t *testing.T) {
+	spec := &parser.OpenInfraSpec{
+		Resources: map[string]parser.Resource{
+			"vm":  {Name: "vm"},
+			"net": {Name: "net"},
+			"dns": {Name: "dns", Dependencies: []parser.Dependency{{DependsOn: []string{"net"}}}},
+			"log": {Name: "log"},
+		},
+		Dependencies: []parser.Dependency{
+			{Resource: "vm", DependsOn: []string{"net", "dns"}},
+		},
+	}
+
+	g, err := Build(spec)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"dns", "log", "net", "vm"}, g.Nodes())
+	assert.Equal(t, []string{"dns", "net"}, g.DependenciesOf("vm"))
+	assert.Equal(t, []string{"dns", "vm"}, g.DependentsOf("net"))
+
+	order, err := g.TopologicalOrder()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"log", "net", "dns", "vm"}, order)
+
+	waves, err := g.Waves()
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"log", "net"}, {"dns"}, {"vm"}}, waves)
+}
+
+func TestBuildUnknownComponent(t *testing.T) {
+	spec := &parser.OpenInfraSpec{
+		Resources: map[string]parser.Resource{"vm": {Name: "vm"}},
+		Dependencies: []parser.Dependency{
+			{Resource: "vm", DependsOn: []string{"net"}},
+		},
+	}
+
+	_, err := Build(spec)
+	assert.EqualError(t, err, `компонент "vm" зависит от несуществующего компонента "net"`)
+
+	// Для разобранной спецификации ошибка указывает на зависимость в файле
+	spec.Dependencies[0].Pos = parser.Position{File: "spec.yaml", Line: 12, Column: 5}
+	_, err = Build(spec)
+	assert.EqualError(t, err, `spec.yaml:12:5: компонент "vm" зависит от несуществующего компонента "net"`)
+}
+
+func TestCycle(t *testing.T) {
+	g := New("a", "b", "c", "d")
+	g.AddEdge("a", "b")
+	g.AddEdge("b", "c")
+	g.AddEdge("c", "a")
+	g.AddEdge("d", "a")
+
+	assert.Equal(t, []string{"a", "b", "c", "a"}, g.FindCycle())
+
+	_, err := g.TopologicalOrder()
+	var cycleErr *CycleError
+	assert.ErrorAs(t, err, &cycleErr)
+	assert.Equal(t, []string{"a", "b", "c", "a"}, cycleErr.Path)
+	assert.EqualError(t, err, "обнаружен цикл зависимостей: a -> b -> c -> a")
+
+	_, err = g.Waves()
+	assert.ErrorAs(t, err, &cycleErr)
+}
+
+func TestSelfDependency(t *testing.T) {
+	g := New()
+	g.AddEdge("a", "a")
+	assert.Equal(t, []string{"a", "a"}, g.FindCycle())
+}
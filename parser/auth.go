@@ -0,0 +1,307 @@
+package parser
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Authenticator добавляет учётные данные в запрос к провайдеру.
+type Authenticator interface {
+	Authenticate(req *http.Request, auth Authentication) error
+}
+
+// AuthenticatorFunc позволяет использовать функцию как Authenticator.
+type AuthenticatorFunc func(req *http.Request, auth Authentication) error
+
+func (f AuthenticatorFunc) Authenticate(req *http.Request, auth Authentication) error {
+	return f(req, auth)
+}
+
+// Методы аутентификации, доступные по умолчанию
+const (
+	// AuthNone — без учётных данных (также пустой method)
+	AuthNone = "none"
+	// AuthAPIKey — api_key в заголовке Authorization: Bearer
+	AuthAPIKey = "api_key"
+	// AuthPassword — username и password как HTTP Basic
+	AuthPassword = "password"
+	// AuthBasic — синоним password
+	AuthBasic = "basic"
+	// AuthBearer — token (или api_key) в заголовке Authorization: Bearer
+	AuthBearer = "bearer"
+	// AuthHeader — api_key в заголовке header (по умолчанию X-API-Key)
+	AuthHeader = "header"
+	// AuthQuery — api_key в параметре запроса param (по умолчанию api_key)
+	AuthQuery = "query"
+	// AuthOAuth2 — OAuth2 client credentials с кешированием токена
+	AuthOAuth2 = "oauth2"
+	// AuthHMAC — подпись запроса HMAC-SHA256 ключом secret
+	AuthHMAC = "hmac"
+)
+
+var (
+	authMu         sync.RWMutex
+	authenticators = map[string]Authenticator{
+		"":           AuthenticatorFunc(noAuth),
+		AuthNone:     AuthenticatorFunc(noAuth),
+		AuthAPIKey:   AuthenticatorFunc(bearerAuth),
+		AuthPassword: AuthenticatorFunc(basicAuth),
+		AuthBasic:    AuthenticatorFunc(basicAuth),
+		AuthBearer:   AuthenticatorFunc(bearerAuth),
+		AuthHeader:   AuthenticatorFunc(headerAuth),
+		AuthQuery:    AuthenticatorFunc(queryAuth),
+		AuthOAuth2:   &OAuth2Authenticator{},
+		AuthHMAC:     AuthenticatorFunc(hmacAuth),
+	}
+)
+
+// RegisterAuthenticator регистрирует Authenticator для метода method,
+// заменяя существующий, если он уже есть.
+func RegisterAuthenticator(method string, a Authenticator) {
+	authMu.Lock()
+	defer authMu.Unlock()
+	authenticators[method] = a
+}
+
+// LookupAuthenticator возвращает Authenticator для метода method
+// или ошибку, если метод неизвестен.
+func LookupAuthenticator(method string) (Authenticator, error) {
+	authMu.RLock()
+	defer authMu.RUnlock()
+	if a, exists := authenticators[method]; exists {
+		return a, nil
+	}
+	return nil, fmt.Errorf("ошибка: неизвестный метод аутентификации %q", method)
+}
+
+func noAuth(*http.Request, Authentication) error {
+	return nil
+}
+
+func basicAuth(req *http.Request, auth Authentication) error {
+	req.SetBasicAuth(auth.Username, auth.Password)
+	return nil
+}
+
+func bearerAuth(req *http.Request, auth Authentication) error {
+	token := auth.Token
+	if token == "" {
+		token = auth.APIKey
+	}
+	if token == "" {
+		return errors.New("не указан token")
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+func headerAuth(req *http.Request, auth Authentication) error {
+	if auth.APIKey == "" {
+		return errors.New("не указан api_key")
+	}
+	name := auth.Header
+	if name == "" {
+		name = "X-API-Key"
+	}
+	req.Header.Set(name, auth.APIKey)
+	return nil
+}
+
+func queryAuth(req *http.Request, auth Authentication) error {
+	if auth.APIKey == "" {
+		return errors.New("не указан api_key")
+	}
+	name := auth.Param
+	if name == "" {
+		name = "api_key"
+	}
+	q := req.URL.Query()
+	q.Set(name, auth.APIKey)
+	req.URL.RawQuery = q.Encode()
+	return nil
+}
+
+// now подменяется в тестах.
+var now = time.Now
+
+// hmacAuth подписывает запрос. Подписывается строка
+//
+//	METHOD\nPATH?QUERY\nTIMESTAMP\nhex(sha256(BODY))
+//
+// Подпись передаётся в заголовке X-Signature (hex HMAC-SHA256), время —
+// в X-Timestamp (Unix-секунды), а api_key, если указан, — в X-Key-Id.
+func hmacAuth(req *http.Request, auth Authentication) error {
+	if auth.Secret == "" {
+		return errors.New("не указан secret")
+	}
+
+	var body []byte
+	if req.GetBody != nil {
+		rc, err := req.GetBody()
+		if err != nil {
+			return fmt.Errorf("не удалось прочитать тело запроса: %w", err)
+		}
+		body, err = io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return fmt.Errorf("не удалось прочитать тело запроса: %w", err)
+		}
+	}
+
+	timestamp := strconv.FormatInt(now().Unix(), 10)
+	bodyHash := sha256.Sum256(body)
+	payload := strings.Join([]string{
+		req.Method,
+		req.URL.RequestURI(),
+		timestamp,
+		hex.EncodeToString(bodyHash[:]),
+	}, "\n")
+
+	mac := hmac.New(sha256.New, []byte(auth.Secret))
+	mac.Write([]byte(payload))
+
+	req.Header.Set("X-Timestamp", timestamp)
+	req.Header.Set("X-Signature", hex.EncodeToString(mac.Sum(nil)))
+	if auth.APIKey != "" {
+		req.Header.Set("X-Key-Id", auth.APIKey)
+	}
+	return nil
+}
+
+// OAuth2Authenticator получает токен по OAuth2 client credentials
+// и кеширует его до истечения срока действия. Токены с разными
+// параметрами запрашиваются независимо: медленный сервер токенов
+// не задерживает запросы к другим провайдерам.
+//
+// Токен обновляется только по сроку expires_in: если провайдер отозвал
+// его раньше и ответил 401, запрос не повторяется с новым токеном,
+// а ошибка возвращается вызывающему.
+type OAuth2Authenticator struct {
+	// Client — HTTP-клиент для запроса токена. По умолчанию используется
+	// клиент выполнения запроса (см. HTTPClientFromContext), а без него —
+	// http.DefaultClient.
+	Client *http.Client
+
+	mu     sync.Mutex
+	tokens map[string]*oauth2Entry
+}
+
+// oauth2Entry — кешированный токен; mu удерживается на время его
+// получения, чтобы одновременные запросы не получали токен повторно.
+type oauth2Entry struct {
+	mu    sync.Mutex
+	token oauth2Token
+}
+
+type oauth2Token struct {
+	value   string
+	expires time.Time
+}
+
+// oauth2ExpirySkew — запас, с которым токен обновляется до истечения.
+// Для короткоживущих токенов запас не больше половины срока действия,
+// иначе токен устаревал бы сразу после получения.
+const oauth2ExpirySkew = 30 * time.Second
+
+func (a *OAuth2Authenticator) Authenticate(req *http.Request, auth Authentication) error {
+	if auth.TokenURL == "" || auth.ClientID == "" {
+		return errors.New("не указаны token_url и client_id")
+	}
+
+	key := strings.Join([]string{auth.TokenURL, auth.ClientID, auth.ClientSecret, strings.Join(auth.Scopes, " ")}, "\x00")
+	e := a.entry(key)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.token.value == "" || (!e.token.expires.IsZero() && now().After(e.token.expires)) {
+		token, err := a.fetch(req, auth)
+		if err != nil {
+			return err
+		}
+		e.token = token
+	}
+
+	req.Header.Set("Authorization", "Bearer "+e.token.value)
+	return nil
+}
+
+// entry возвращает запись кеша для ключа key.
+func (a *OAuth2Authenticator) entry(key string) *oauth2Entry {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.tokens == nil {
+		a.tokens = make(map[string]*oauth2Entry)
+	}
+	e, exists := a.tokens[key]
+	if !exists {
+		e = &oauth2Entry{}
+		a.tokens[key] = e
+	}
+	return e
+}
+
+// fetch запрашивает новый токен у token_url.
+func (a *OAuth2Authenticator) fetch(orig *http.Request, auth Authentication) (oauth2Token, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(auth.Scopes) > 0 {
+		form.Set("scope", strings.Join(auth.Scopes, " "))
+	}
+
+	req, err := http.NewRequestWithContext(orig.Context(), http.MethodPost, auth.TokenURL,
+		bytes.NewBufferString(form.Encode()))
+	if err != nil {
+		return oauth2Token{}, fmt.Errorf("ошибка при создании запроса токена: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(auth.ClientID), url.QueryEscape(auth.ClientSecret))
+
+	client := a.Client
+	if client == nil {
+		client = HTTPClientFromContext(orig.Context())
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return oauth2Token{}, fmt.Errorf("ошибка при запросе токена: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return oauth2Token{}, fmt.Errorf("ошибка при чтении ответа с токеном: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return oauth2Token{}, fmt.Errorf("сервер токенов вернул статус %s", resp.Status)
+	}
+
+	var payload struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return oauth2Token{}, fmt.Errorf("ошибка при декодировании ответа с токеном: %w", err)
+	}
+	if payload.AccessToken == "" {
+		return oauth2Token{}, errors.New("сервер токенов не вернул access_token")
+	}
+
+	token := oauth2Token{value: payload.AccessToken}
+	if payload.ExpiresIn > 0 {
+		lifetime := time.Duration(payload.ExpiresIn) * time.Second
+		token.expires = now().Add(lifetime - min(oauth2ExpirySkew, lifetime/2))
+	}
+	return token, nil
+}
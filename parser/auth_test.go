@@ -0,0 +1,255 @@
+package parser
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// authRequest выполняет возможность провайдера с указанной аутентификацией
+// и возвращает запрос, который получил сервер.
+func authRequest(t *testing.T, auth Authentication, params map[string]interface{}) (*http.Request, string, error) {
+	t.Helper()
+
+	var got *http.Request
+	var body string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		got, body = r, string(data)
+	}))
+	defer ts.Close()
+
+	provider := Provider{
+		Name:       "p",
+		Connection: Connection{Endpoint: ts.URL, Authentication: auth},
+		Capabilities: []Capability{
+			{Name: "create", Method: "POST", Endpoint: "/vms?dry_run=1", Parameters: []Parameter{
+				{Name: "cpu", Type: TypeInteger},
+			}},
+		},
+	}
+	_, err := provider.ExecuteCapabilityContext(context.Background(), "create", params)
+	return got, body, err
+}
+
+func TestBuiltinAuthenticators(t *testing.T) {
+	req, _, err := authRequest(t, Authentication{Method: AuthAPIKey, APIKey: "k1"}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "Bearer k1", req.Header.Get("Authorization"))
+
+	req, _, err = authRequest(t, Authentication{Method: AuthBearer, Token: "t1"}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "Bearer t1", req.Header.Get("Authorization"))
+
+	req, _, err = authRequest(t, Authentication{Method: AuthPassword, Username: "admin", Password: "secret"}, nil)
+	assert.NoError(t, err)
+	user, pass, ok := req.BasicAuth()
+	assert.True(t, ok)
+	assert.Equal(t, "admin", user)
+	assert.Equal(t, "secret", pass)
+
+	req, _, err = authRequest(t, Authentication{Method: AuthHeader, APIKey: "k2", Header: "X-Token"}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "k2", req.Header.Get("X-Token"))
+
+	req, _, err = authRequest(t, Authentication{Method: AuthQuery, APIKey: "k3"}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "k3", req.URL.Query().Get("api_key"))
+	assert.Equal(t, "1", req.URL.Query().Get("dry_run"))
+
+	req, _, err = authRequest(t, Authentication{}, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, req.Header.Get("Authorization"))
+}
+
+func TestUnknownAuthenticator(t *testing.T) {
+	_, _, err := authRequest(t, Authentication{Method: "kerberos"}, nil)
+	assert.EqualError(t, err, `ошибка: неизвестный метод аутентификации "kerberos"`)
+
+	_, _, err = authRequest(t, Authentication{Method: AuthBearer}, nil)
+	assert.EqualError(t, err, "ошибка аутентификации (bearer): не указан token")
+}
+
+func TestRegisterAuthenticator(t *testing.T) {
+	RegisterAuthenticator("custom", AuthenticatorFunc(func(req *http.Request, auth Authentication) error {
+		req.Header.Set("X-Custom", auth.Username)
+		return nil
+	}))
+	defer func() {
+		authMu.Lock()
+		delete(authenticators, "custom")
+		authMu.Unlock()
+	}()
+
+	req, _, err := authRequest(t, Authentication{Method: "custom", Username: "bob"}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "bob", req.Header.Get("X-Custom"))
+}
+
+func TestHMACAuthenticator(t *testing.T) {
+	now = func() time.Time { return time.Unix(1700000000, 0) }
+	defer func() { now = time.Now }()
+
+	req, body, err := authRequest(t, Authentication{Method: AuthHMAC, APIKey: "key-1", Secret: "s3cr3t"},
+		map[string]interface{}{"cpu": 2})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"cpu":2}`, body)
+	assert.Equal(t, "1700000000", req.Header.Get("X-Timestamp"))
+	assert.Equal(t, "key-1", req.Header.Get("X-Key-Id"))
+
+	bodyHash := sha256.Sum256([]byte(body))
+	mac := hmac.New(sha256.New, []byte("s3cr3t"))
+	mac.Write([]byte("POST\n/vms?dry_run=1\n1700000000\n" + hex.EncodeToString(bodyHash[:])))
+	assert.Equal(t, hex.EncodeToString(mac.Sum(nil)), req.Header.Get("X-Signature"))
+}
+
+func TestOAuth2Authenticator(t *testing.T) {
+	var issued int32
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		id, secret, _ := r.BasicAuth()
+		if id != "client" || secret != "secret" || r.Form.Get("grant_type") != "client_credentials" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		n := atomic.AddInt32(&issued, 1)
+		fmt.Fprintf(w, `{"access_token": "token-%d", "token_type": "bearer", "expires_in": 60}`, n)
+	}))
+	defer tokenServer.Close()
+
+	current := time.Unix(1700000000, 0)
+	now = func() time.Time { return current }
+	defer func() { now = time.Now }()
+
+	a := &OAuth2Authenticator{Client: tokenServer.Client()}
+	auth := Authentication{Method: AuthOAuth2, TokenURL: tokenServer.URL, ClientID: "client", ClientSecret: "secret", Scopes: []string{"vms"}}
+
+	authorize := func() string {
+		req := httptest.NewRequest(http.MethodGet, "http://provider/vms", nil)
+		assert.NoError(t, a.Authenticate(req, auth))
+		return req.Header.Get("Authorization")
+	}
+
+	assert.Equal(t, "Bearer token-1", authorize())
+	assert.Equal(t, "Bearer token-1", authorize())
+
+	// Токен живёт 60 секунд, обновляется с запасом в 30 секунд
+	current = current.Add(45 * time.Second)
+	assert.Equal(t, "Bearer token-2", authorize())
+	assert.Equal(t, int32(2), atomic.LoadInt32(&issued))
+
+	auth.ClientSecret = "wrong"
+	req := httptest.NewRequest(http.MethodGet, "http://provider/vms", nil)
+	assert.EqualError(t, a.Authenticate(req, auth), "сервер токенов вернул статус 401 Unauthorized")
+}
+
+func TestOAuth2ShortLivedToken(t *testing.T) {
+	var issued int32
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&issued, 1)
+		fmt.Fprintf(w, `{"access_token": "token-%d", "expires_in": 10}`, n)
+	}))
+	defer tokenServer.Close()
+
+	current := time.Unix(1700000000, 0)
+	now = func() time.Time { return current }
+	defer func() { now = time.Now }()
+
+	a := &OAuth2Authenticator{Client: tokenServer.Client()}
+	auth := Authentication{Method: AuthOAuth2, TokenURL: tokenServer.URL, ClientID: "client"}
+	authorize := func() string {
+		req := httptest.NewRequest(http.MethodGet, "http://provider/vms", nil)
+		assert.NoError(t, a.Authenticate(req, auth))
+		return req.Header.Get("Authorization")
+	}
+
+	// Запас для токена на 10 секунд — 5 секунд, а не 30
+	assert.Equal(t, "Bearer token-1", authorize())
+	current = current.Add(4 * time.Second)
+	assert.Equal(t, "Bearer token-1", authorize())
+	current = current.Add(2 * time.Second)
+	assert.Equal(t, "Bearer token-2", authorize())
+	assert.Equal(t, int32(2), atomic.LoadInt32(&issued))
+}
+
+func TestOAuth2UsesExecTransport(t *testing.T) {
+	// Токены глобального аутентификатора кешируются: адрес уникален для запуска
+	tokenURL := fmt.Sprintf("http://token.invalid/%d", time.Now().UnixNano())
+	var requests []string
+	rt := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		requests = append(requests, r.Method+" "+r.URL.String())
+		body := "ok"
+		if r.URL.Host == "token.invalid" {
+			body = `{"access_token": "from-transport"}`
+		} else {
+			assert.Equal(t, "Bearer from-transport", r.Header.Get("Authorization"))
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Header:     make(http.Header),
+		}, nil
+	})
+
+	provider := Provider{
+		Name: "fake",
+		Connection: Connection{
+			Endpoint: "http://provider.invalid",
+			Authentication: Authentication{
+				Method:   AuthOAuth2,
+				TokenURL: tokenURL,
+				ClientID: "client",
+			},
+		},
+		Capabilities: []Capability{{Name: "list", Method: "GET", Endpoint: "/vms"}},
+	}
+	_, err := provider.ExecuteCapabilityContext(context.Background(), "list", nil, WithTransport(rt))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"POST " + tokenURL,
+		"GET http://provider.invalid/vms",
+	}, requests)
+}
+
+func TestOAuth2TokenServersIndependent(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		fmt.Fprint(w, `{"access_token": "slow"}`)
+	}))
+	defer slow.Close()
+	defer close(release)
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"access_token": "fast"}`)
+	}))
+	defer fast.Close()
+
+	a := &OAuth2Authenticator{}
+	go func() {
+		req := httptest.NewRequest(http.MethodGet, "http://provider/vms", nil)
+		a.Authenticate(req, Authentication{TokenURL: slow.URL, ClientID: "client"})
+	}()
+
+	done := make(chan error, 1)
+	go func() {
+		req := httptest.NewRequest(http.MethodGet, "http://provider/vms", nil)
+		done <- a.Authenticate(req, Authentication{TokenURL: fast.URL, ClientID: "client"})
+	}()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("запрос токена ждёт другой сервер токенов")
+	}
+}
@@ -0,0 +1,216 @@
+package parser
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// linePrefix — номер строки в ошибке yaml.v3: у ошибки свойства
+// уже есть позиция значения.
+var linePrefix = regexp.MustCompile(`(^|; )line \d+: `)
+
+var (
+	componentMu    sync.RWMutex
+	componentTypes = map[string]reflect.Type{}
+)
+
+// RegisterComponentType связывает тип компонента typ (например,
+// virtual_machine) со структурой его свойств. properties — значение
+// или указатель на структуру, например VirtualMachine{}; ключи свойств
+// сопоставляются полям по тегам yaml, обязательные поля отмечаются тегом
+// openinfra:"required". После регистрации свойства компонентов этого типа
+// проверяются при разборе: неизвестные и незаполненные обязательные
+// свойства, а также значения не того типа считаются ошибкой, в том числе
+// во вложенных структурах. Ключи расширений вида x-owner не проверяются.
+// Повторная регистрация заменяет структуру, nil снимает регистрацию.
+// Если properties не структура, возвращается ошибка, а регистрация
+// не меняется.
+func RegisterComponentType(typ string, properties interface{}) error {
+	var t reflect.Type
+	if properties != nil {
+		if t = structType(reflect.TypeOf(properties)); t == nil {
+			return fmt.Errorf("ошибка: RegisterComponentType(%q) ожидает структуру, получено %T", typ, properties)
+		}
+	}
+
+	componentMu.Lock()
+	defer componentMu.Unlock()
+	if t == nil {
+		delete(componentTypes, typ)
+		return nil
+	}
+	componentTypes[typ] = t
+	return nil
+}
+
+func lookupComponentType(typ string) (reflect.Type, bool) {
+	componentMu.RLock()
+	defer componentMu.RUnlock()
+	t, exists := componentTypes[typ]
+	return t, exists
+}
+
+// DecodeProperties декодирует свойства компонента r в структуру, на которую
+// указывает out, по тем же правилам, что и проверка при разборе (см.
+// RegisterComponentType). Регистрировать тип компонента для этого
+// не обязательно. Ошибки свойств возвращаются как ValidationErrors
+// с именем компонента и свойства.
+func DecodeProperties(r Resource, out interface{}) error {
+	v := reflect.ValueOf(out)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("ошибка: DecodeProperties ожидает указатель на структуру, получено %T", out)
+	}
+
+	n := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	if len(r.Properties) > 0 {
+		var err error
+		if n, err = encodeNode(r.Properties); err != nil {
+			return fmt.Errorf("компонент %q: %w", r.Name, err)
+		}
+	}
+
+	// У закодированных узлов нет позиций: ошибки указывают на компонент
+	c := &propertyChecker{
+		component: r.Name,
+		fileOf:    func(*yaml.Node) string { return r.Pos.File },
+		at:        r.Pos,
+	}
+	c.check(n, v.Elem().Type(), yamlPath{"properties"}, "")
+	if len(c.errs) > 0 {
+		return c.errs
+	}
+	if err := n.Decode(out); err != nil {
+		return fmt.Errorf("компонент %q: %s", r.Name, decodeErrorDetail(err))
+	}
+	return nil
+}
+
+// checkComponentProperties проверяет свойства компонентов документа root,
+// для типов которых зарегистрирована структура.
+func checkComponentProperties(root *yaml.Node, fileOf func(*yaml.Node) string) ValidationErrors {
+	var errs ValidationErrors
+	for i, item := range sectionItems(resolveAlias(root), "components") {
+		t, exists := lookupComponentType(scalarValue(mappingValue(item, "type")))
+		if !exists {
+			continue
+		}
+		c := &propertyChecker{
+			component: scalarValue(mappingValue(item, "name")),
+			fileOf:    fileOf,
+			at:        nodePosition(fileOf(item), item),
+		}
+		c.check(mappingValue(item, "properties"), t, yamlPath{"components", i, "properties"}, "")
+		errs = append(errs, c.errs...)
+	}
+	return errs
+}
+
+type propertyChecker struct {
+	component string
+	fileOf    func(*yaml.Node) string
+	// at — позиция компонента для ошибок без своего узла
+	at   Position
+	errs ValidationErrors
+}
+
+// check сверяет свойства props со структурой t. prefix — путь к свойствам
+// внутри properties для сообщений, например disk. у вложенной структуры.
+func (c *propertyChecker) check(props *yaml.Node, t reflect.Type, path yamlPath, prefix string) {
+	t = structType(t)
+	props = resolveAlias(props)
+	if props != nil && props.ShortTag() == "!!null" {
+		props = nil
+	}
+	if props != nil && props.Kind != yaml.MappingNode {
+		c.report(CodeInvalidValue, props, path, "свойства компонента %q должны быть отображением", c.component)
+		return
+	}
+
+	present := make(map[string]bool)
+	if props != nil {
+		for i := 0; i+1 < len(props.Content); i += 2 {
+			key, value := props.Content[i], props.Content[i+1]
+			present[key.Value] = true
+
+			f, known := yamlFieldByKey(t, key.Value)
+			if !known {
+				if extensionKey.MatchString(key.Value) {
+					continue
+				}
+				msg := fmt.Sprintf("у компонента %q неизвестное свойство %s", c.component, prefix+key.Value)
+				if s := suggestField(key.Value, t); s != "" {
+					msg += fmt.Sprintf(", возможно, имелось в виду %s", prefix+s)
+				}
+				c.report(CodeUnknownField, key, path.with(key.Value), "%s", msg)
+				continue
+			}
+			c.value(value, f.Type, path.with(key.Value), prefix+key.Value)
+		}
+	}
+
+	for _, f := range yamlFields(t) {
+		if isRequiredProperty(t.FieldByIndex(f.Index)) && !present[f.Key] {
+			c.report(CodeMissingField, props, path.with(f.Key),
+				"у компонента %q не указано обязательное свойство %s", c.component, prefix+f.Key)
+		}
+	}
+}
+
+// value проверяет значение свойства name с типом t: вложенные структуры
+// и списки структур проверяются по полям, остальное — декодированием.
+func (c *propertyChecker) value(value *yaml.Node, t reflect.Type, path yamlPath, name string) {
+	n := resolveAlias(value)
+	switch {
+	case n == nil:
+	case n.Kind == yaml.MappingNode && nestedStruct(t):
+		c.check(n, t, path, name+".")
+		return
+	case n.Kind == yaml.SequenceNode && t.Kind() == reflect.Slice && nestedStruct(t.Elem()):
+		for i, item := range n.Content {
+			c.value(item, t.Elem(), path.with(i), fmt.Sprintf("%s[%d]", name, i))
+		}
+		return
+	}
+	if err := value.Decode(reflect.New(t).Interface()); err != nil {
+		c.report(CodeInvalidValue, value, path, "свойство %s компонента %q: %s",
+			name, c.component, linePrefix.ReplaceAllString(decodeErrorDetail(err), "$1"))
+	}
+}
+
+// nestedStruct сообщает, что свойства типа t проверяются по полям:
+// это структура с полями yaml, которая не разбирает YAML сама.
+func nestedStruct(t reflect.Type) bool {
+	t = structType(t)
+	if t == nil || len(yamlFields(t)) == 0 {
+		return false
+	}
+	return !reflect.PointerTo(t).Implements(reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem())
+}
+
+func (c *propertyChecker) report(code ErrorCode, n *yaml.Node, path yamlPath, format string, args ...interface{}) {
+	pos := c.at
+	if n != nil && n.Line > 0 {
+		pos = nodePosition(c.fileOf(n), n)
+	}
+	c.errs = append(c.errs, &ValidationError{
+		Code:     code,
+		Message:  fmt.Sprintf(format, args...),
+		Path:     path.String(),
+		Position: pos,
+	})
+}
+
+// isRequiredProperty сообщает, отмечено ли поле тегом openinfra:"required".
+func isRequiredProperty(f reflect.StructField) bool {
+	for _, opt := range strings.Split(f.Tag.Get("openinfra"), ",") {
+		if opt == "required" {
+			return true
+		}
+	}
+	return false
+}
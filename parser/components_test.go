@@ -0,0 +1,225 @@
+package parser
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testVM struct {
+	CPU     int           `yaml:"cpu" openinfra:"required"`
+	Memory  string        `yaml:"memory" openinfra:"required"`
+	Disks   []string      `yaml:"disks,omitempty"`
+	Network string        `yaml:"network,omitempty"`
+	Boot    time.Duration `yaml:"boot_timeout,omitempty"`
+}
+
+// registerTestVM регистрирует testVM для типа test_vm на время теста.
+func registerTestVM(t *testing.T) {
+	require.NoError(t, RegisterComponentType("test_vm", &testVM{}))
+	t.Cleanup(func() { _ = RegisterComponentType("test_vm", nil) })
+}
+
+const componentTypesYAML = `openinfra: 1.1.0
+providers:
+  - name: p
+    type: t
+components:
+  - name: vm
+    type: test_vm
+    provider: p
+    properties:
+      cpu: 2
+      memory: 4GB
+      disks: [root, data]
+      boot_timeout: 30s
+      x-owner: team
+  - name: net
+    type: network
+    provider: p
+    properties:
+      anything: goes
+`
+
+func TestDecodeProperties(t *testing.T) {
+	registerTestVM(t)
+
+	spec, err := ParseBytes([]byte(componentTypesYAML))
+	require.NoError(t, err)
+
+	var vm testVM
+	require.NoError(t, DecodeProperties(spec.Resources["vm"], &vm))
+	assert.Equal(t, testVM{CPU: 2, Memory: "4GB", Disks: []string{"root", "data"}, Boot: 30 * time.Second}, vm)
+
+	// Регистрация не нужна: структура задаётся вызывающим
+	var net struct {
+		Anything string `yaml:"anything"`
+	}
+	require.NoError(t, DecodeProperties(spec.Resources["net"], &net))
+	assert.Equal(t, "goes", net.Anything)
+}
+
+func TestDecodePropertiesErrors(t *testing.T) {
+	r := Resource{
+		Name:       "vm",
+		Properties: map[string]interface{}{"cpu": "two", "memroy": "4GB"},
+		Pos:        Position{File: "spec.yaml", Line: 7, Column: 5},
+	}
+
+	var vm testVM
+	err := DecodeProperties(r, &vm)
+	var errs ValidationErrors
+	require.True(t, errors.As(err, &errs))
+	assert.EqualError(t, err, "spec.yaml:7:5: свойство cpu компонента \"vm\": cannot unmarshal !!str `two` into int\n"+
+		"spec.yaml:7:5: у компонента \"vm\" неизвестное свойство memroy, возможно, имелось в виду memory\n"+
+		"spec.yaml:7:5: у компонента \"vm\" не указано обязательное свойство memory")
+	assert.Equal(t, "properties.cpu", errs[0].Path)
+	assert.Equal(t, CodeMissingField, errs[2].Code)
+
+	err = DecodeProperties(Resource{Name: "vm"}, &vm)
+	assert.EqualError(t, err, "properties.cpu: у компонента \"vm\" не указано обязательное свойство cpu\n"+
+		"properties.memory: у компонента \"vm\" не указано обязательное свойство memory")
+
+	assert.EqualError(t, DecodeProperties(r, vm),
+		"ошибка: DecodeProperties ожидает указатель на структуру, получено parser.testVM")
+}
+
+func TestComponentTypeCheckedAtParse(t *testing.T) {
+	registerTestVM(t)
+
+	data := []byte(`openinfra: 1.1.0
+components:
+  - name: vm
+    type: test_vm
+    provider: p
+    properties:
+      cpu: many
+      memroy: 4GB
+  - name: bare
+    type: test_vm
+    provider: p
+  - name: other
+    type: unregistered
+    provider: p
+    properties:
+      cpu: many
+`)
+	_, err := ParseBytes(data, WithSource("spec.yaml"))
+	var errs ValidationErrors
+	require.True(t, errors.As(err, &errs))
+
+	type diagnostic struct {
+		Code     ErrorCode
+		Path     string
+		Position string
+	}
+	var got []diagnostic
+	for _, e := range errs {
+		got = append(got, diagnostic{e.Code, e.Path, e.Position.String()})
+	}
+	assert.Equal(t, []diagnostic{
+		{CodeInvalidValue, "components[0].properties.cpu", "spec.yaml:7:12"},
+		{CodeUnknownField, "components[0].properties.memroy", "spec.yaml:8:7"},
+		{CodeMissingField, "components[0].properties.memory", "spec.yaml:7:7"},
+		{CodeMissingField, "components[1].properties.cpu", "spec.yaml:9:5"},
+		{CodeMissingField, "components[1].properties.memory", "spec.yaml:9:5"},
+	}, got)
+	assert.Equal(t, `у компонента "vm" неизвестное свойство memroy, возможно, имелось в виду memory`, errs[1].Message)
+
+	// В режиме восстановления спецификация возвращается вместе с ошибками
+	spec, err := ParseBytes(data, WithRecovery())
+	require.NotNil(t, spec)
+	assert.Len(t, spec.Resources, 3)
+	require.True(t, errors.As(err, &errs))
+	assert.Len(t, errs, 5)
+
+	// Без регистрации свойства не проверяются
+	require.NoError(t, RegisterComponentType("test_vm", nil))
+	_, err = ParseBytes(data)
+	assert.NoError(t, err)
+}
+
+func TestRegisterComponentTypeRejectsNonStruct(t *testing.T) {
+	registerTestVM(t)
+
+	err := RegisterComponentType("test_vm", map[string]interface{}{})
+	assert.EqualError(t, err, `ошибка: RegisterComponentType("test_vm") ожидает структуру, получено map[string]interface {}`)
+
+	// Прежняя регистрация сохраняется
+	_, exists := lookupComponentType("test_vm")
+	assert.True(t, exists)
+}
+
+type testDisk struct {
+	Size string `yaml:"size" openinfra:"required"`
+	Kind string `yaml:"kind,omitempty"`
+}
+
+type testBase struct {
+	Region string `yaml:"region" openinfra:"required"`
+}
+
+type testServer struct {
+	testBase `yaml:",inline"`
+	Boot     *testDisk  `yaml:"boot"`
+	Disks    []testDisk `yaml:"disks,omitempty"`
+}
+
+type testLabeled struct {
+	Name   string            `yaml:"name"`
+	Labels map[string]string `yaml:",inline"`
+}
+
+func TestComponentTypeNestedAndInline(t *testing.T) {
+	require.NoError(t, RegisterComponentType("test_server", testServer{}))
+	t.Cleanup(func() { _ = RegisterComponentType("test_server", nil) })
+
+	data := []byte(`openinfra: 1.1.0
+components:
+  - name: web
+    type: test_server
+    provider: p
+    properties:
+      region: eu
+      boot:
+        size: 10GB
+      disks:
+        - size: 20GB
+          kind: ssd
+  - name: db
+    type: test_server
+    provider: p
+    properties:
+      regoin: eu
+      boot:
+        sise: 10GB
+      disks:
+        - kind: [ssd]
+`)
+	_, err := ParseBytes(data, WithSource("spec.yaml"))
+	var errs ValidationErrors
+	require.True(t, errors.As(err, &errs))
+
+	var got []string
+	for _, e := range errs {
+		got = append(got, e.Error())
+	}
+	assert.Equal(t, []string{
+		`spec.yaml:17:7: у компонента "db" неизвестное свойство regoin, возможно, имелось в виду region`,
+		`spec.yaml:19:9: у компонента "db" неизвестное свойство boot.sise, возможно, имелось в виду boot.size`,
+		`spec.yaml:19:9: у компонента "db" не указано обязательное свойство boot.size`,
+		"spec.yaml:21:17: свойство disks[0].kind компонента \"db\": cannot unmarshal !!seq into string",
+		`spec.yaml:21:11: у компонента "db" не указано обязательное свойство disks[0].size`,
+		`spec.yaml:17:7: у компонента "db" не указано обязательное свойство region`,
+	}, got)
+	assert.Equal(t, "components[1].properties.disks[0].size", errs[4].Path)
+
+	// Встроенное отображение принимает любые ключи
+	var labeled testLabeled
+	r := Resource{Name: "app", Properties: map[string]interface{}{"name": "app", "team": "core"}}
+	require.NoError(t, DecodeProperties(r, &labeled))
+	assert.Equal(t, map[string]string{"team": "core"}, labeled.Labels)
+}
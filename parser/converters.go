@@ -1,13 +1,65 @@
 package parser
 
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+)
+
 // convertToInt безопасно преобразует значение в целое число
 func convertToInt(value interface{}) int {
+	if f, ok := value.(float64); ok {
+		return int(f)
+	}
+	n, _ := toInt(value)
+	return n
+}
+
+// toInt преобразует значение в целое число и сообщает, удалось ли это.
+// Дробные числа принимаются, только если у них нет дробной части.
+func toInt(value interface{}) (int, bool) {
 	switch v := value.(type) {
 	case int:
-		return v
+		return v, true
+	case int64:
+		return int(v), true
+	case uint64:
+		return int(v), true
 	case float64:
-		return int(v)
+		if v != math.Trunc(v) {
+			return 0, false
+		}
+		return int(v), true
+	case json.Number:
+		n, err := strconv.Atoi(v.String())
+		if err != nil {
+			f, ferr := v.Float64()
+			if ferr != nil {
+				return 0, false
+			}
+			return toInt(f)
+		}
+		return n, true
+	default:
+		return 0, false
+	}
+}
+
+// toFloat преобразует числовое значение в float64.
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
 	default:
-		return 0
+		return 0, false
 	}
 }
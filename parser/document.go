@@ -0,0 +1,675 @@
+package parser
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Document — редактируемый документ OpenInfra на основе дерева yaml.Node.
+// В отличие от связки OpenInfraSpec и GenerateYAML правки через Document
+// сохраняют комментарии, порядок ключей, якоря и ключи, неизвестные парсеру.
+// Пустые строки между блоками yaml.v3 не сохраняет.
+type Document struct {
+	root   *yaml.Node
+	source string
+	// files — файлы узлов, полученных не из source (см. MergeFiles)
+	files map[*yaml.Node]string
+}
+
+// ParseDocument разбирает YAML в редактируемый документ.
+func ParseDocument(data []byte, opts ...Option) (*Document, error) {
+	o := newOptions(opts)
+
+	// Документ читается через Decoder, чтобы не потерять при записи
+	// следующие документы потока: Document описывает ровно один
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	var root yaml.Node
+	if err := dec.Decode(&root); err != nil && err != io.EOF {
+		return nil, o.yamlError(err)
+	}
+	var next yaml.Node
+	if err := dec.Decode(&next); err != io.EOF {
+		if err != nil {
+			return nil, o.yamlError(err)
+		}
+		return nil, o.yamlError(errors.New("найдено несколько документов, ожидается один"))
+	}
+	return newDocument(&root, o.source)
+}
+
+// ReadDocumentFile читает файл OpenInfra в редактируемый документ.
+func ReadDocumentFile(filename string) (*Document, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fileNotFound(filename, err)
+		}
+		return nil, fmt.Errorf("ошибка при чтении файла %s: %w", filename, err)
+	}
+	return ParseDocument(data, WithSource(filename))
+}
+
+// newDocument проверяет, что корень документа — отображение, и создаёт
+// его для пустого документа.
+func newDocument(root *yaml.Node, source string) (*Document, error) {
+	if root.Kind == 0 {
+		root.Kind = yaml.DocumentNode
+	}
+	if root.Kind != yaml.DocumentNode {
+		root = &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{root}}
+	}
+	if len(root.Content) == 0 {
+		root.Content = []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}
+	}
+	if root.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("ошибка: корень документа %s должен быть отображением", source)
+	}
+	return &Document{root: root, source: source}, nil
+}
+
+// Node возвращает корневой узел документа (yaml.DocumentNode).
+func (d *Document) Node() *yaml.Node {
+	return d.root
+}
+
+// Source возвращает метку источника документа.
+func (d *Document) Source() string {
+	return d.source
+}
+
+// Bytes возвращает текст документа с отступом в два пробела.
+func (d *Document) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(d.root); err != nil {
+		return nil, fmt.Errorf("ошибка при генерации YAML: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("ошибка при генерации YAML: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Spec разбирает текущее состояние документа в OpenInfraSpec.
+func (d *Document) Spec() (*OpenInfraSpec, error) {
+	return parseNode(d.root, &options{source: d.source, files: d.files})
+}
+
+// fileOf возвращает файл, из которого получен узел n.
+func (d *Document) fileOf(n *yaml.Node) string {
+	if file, exists := d.files[n]; exists {
+		return file
+	}
+	return d.source
+}
+
+// Migrate переписывает документ в форму текущей версии формата:
+// переводит устаревшие connection_details и действия-строки, применяет
+// миграции и обновляет поле openinfra. Возвращает предупреждения
+// о каждом исправленном месте.
+func (d *Document) Migrate() (ValidationErrors, error) {
+	o := &options{source: d.source}
+	_, warnings, err := o.migrate(d.root, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if n := resolveAlias(mappingValue(d.root, "openinfra")); n != nil {
+		current := CurrentVersion()
+		if v, err := ParseVersion(n.Value); err == nil && v.Compare(current) < 0 {
+			n.Value, n.Tag, n.Style = current.String(), "!!str", 0
+		}
+	}
+	return warnings, nil
+}
+
+// AddProvider добавляет провайдера в конец списка providers.
+func (d *Document) AddProvider(p Provider) error {
+	if p.Name == "" {
+		return errors.New("ошибка: у провайдера не указано имя")
+	}
+	seq, err := d.section("providers")
+	if err != nil {
+		return err
+	}
+	if _, item := findItem(seq, "name", p.Name); item != nil {
+		return fmt.Errorf("ошибка: провайдер %q уже существует", p.Name)
+	}
+	return appendItem(seq, exactProvider(p))
+}
+
+// UpdateProvider изменяет провайдера name функцией fn. Неизменённые
+// значения сохраняют своё оформление в документе.
+func (d *Document) UpdateProvider(name string, fn func(*Provider) error) error {
+	item, err := d.provider(name)
+	if err != nil {
+		return err
+	}
+	var p Provider
+	return updateItem(item, &p, func() error { return fn(&p) })
+}
+
+// RemoveProvider удаляет провайдера name.
+func (d *Document) RemoveProvider(name string) error {
+	if !removeItem(d.lookup("providers"), "name", name) {
+		return fmt.Errorf("ошибка: провайдер %q не найден", name)
+	}
+	return nil
+}
+
+// AddComponent добавляет компонент в конец списка components.
+func (d *Document) AddComponent(r Resource) error {
+	if r.Name == "" {
+		return errors.New("ошибка: у компонента не указано имя")
+	}
+	seq, err := d.section("components")
+	if err != nil {
+		return err
+	}
+	if _, item := findItem(seq, "name", r.Name); item != nil {
+		return fmt.Errorf("ошибка: компонент %q уже существует", r.Name)
+	}
+	return appendItem(seq, exactResource(r))
+}
+
+// UpdateComponent изменяет компонент name функцией fn.
+func (d *Document) UpdateComponent(name string, fn func(*Resource) error) error {
+	item, err := d.component(name)
+	if err != nil {
+		return err
+	}
+	var r Resource
+	return updateItem(item, &r, func() error { return fn(&r) })
+}
+
+// RemoveComponent удаляет компонент name.
+func (d *Document) RemoveComponent(name string) error {
+	if !removeItem(d.lookup("components"), "name", name) {
+		return fmt.Errorf("ошибка: компонент %q не найден", name)
+	}
+	return nil
+}
+
+// AddCapability добавляет возможность провайдеру provider.
+func (d *Document) AddCapability(provider string, c Capability) error {
+	if c.Name == "" {
+		return errors.New("ошибка: у возможности не указано имя")
+	}
+	item, err := d.provider(provider)
+	if err != nil {
+		return err
+	}
+	seq, err := d.ensureKey(item, "capabilities", yaml.SequenceNode)
+	if err != nil {
+		return err
+	}
+	if _, existing := findItem(seq, "name", c.Name); existing != nil {
+		return fmt.Errorf("ошибка: возможность %q провайдера %q уже существует", c.Name, provider)
+	}
+	return appendItem(seq, exactCapability(c))
+}
+
+// UpdateCapability изменяет возможность name провайдера provider.
+func (d *Document) UpdateCapability(provider, name string, fn func(*Capability) error) error {
+	item, err := d.capability(provider, name)
+	if err != nil {
+		return err
+	}
+	var c Capability
+	return updateItem(item, &c, func() error { return fn(&c) })
+}
+
+// RemoveCapability удаляет возможность name провайдера provider.
+func (d *Document) RemoveCapability(provider, name string) error {
+	item, err := d.provider(provider)
+	if err != nil {
+		return err
+	}
+	if !removeItem(mappingValue(item, "capabilities"), "name", name) {
+		return fmt.Errorf("ошибка: возможность %q не найдена у провайдера %q", name, provider)
+	}
+	return nil
+}
+
+// AddDependency добавляет в раздел dependencies зависимость компонента
+// component от dependsOn. Уже объявленные зависимости не дублируются.
+func (d *Document) AddDependency(component string, dependsOn ...string) error {
+	if component == "" {
+		return errors.New("ошибка: в зависимости не указан компонент")
+	}
+	seq, err := d.section("dependencies")
+	if err != nil {
+		return err
+	}
+	_, item := findItem(seq, "component", component)
+	if item == nil {
+		return appendItem(seq, Dependency{Resource: component, DependsOn: dependsOn})
+	}
+
+	list, err := d.ensureKey(item, "depends_on", yaml.SequenceNode)
+	if err != nil {
+		return err
+	}
+	for _, target := range dependsOn {
+		if indexOfScalar(list, target) < 0 {
+			list.Content = append(list.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: target})
+		}
+	}
+	return nil
+}
+
+// RemoveDependency удаляет зависимость компонента component от dependsOn.
+// Запись без оставшихся зависимостей удаляется целиком.
+func (d *Document) RemoveDependency(component, dependsOn string) error {
+	seq := resolveAlias(d.lookup("dependencies"))
+	i, item := findItem(seq, "component", component)
+	list := resolveAlias(mappingValue(item, "depends_on"))
+	j := indexOfScalar(list, dependsOn)
+	if j < 0 {
+		return fmt.Errorf("ошибка: зависимость %q от %q не найдена", component, dependsOn)
+	}
+
+	list.Content = append(list.Content[:j], list.Content[j+1:]...)
+	if len(list.Content) == 0 {
+		seq.Content = append(seq.Content[:i], seq.Content[i+1:]...)
+	}
+	return nil
+}
+
+func (d *Document) provider(name string) (*yaml.Node, error) {
+	if _, item := findItem(d.lookup("providers"), "name", name); item != nil {
+		return item, nil
+	}
+	return nil, fmt.Errorf("ошибка: провайдер %q не найден", name)
+}
+
+func (d *Document) component(name string) (*yaml.Node, error) {
+	if _, item := findItem(d.lookup("components"), "name", name); item != nil {
+		return item, nil
+	}
+	return nil, fmt.Errorf("ошибка: компонент %q не найден", name)
+}
+
+func (d *Document) capability(provider, name string) (*yaml.Node, error) {
+	item, err := d.provider(provider)
+	if err != nil {
+		return nil, err
+	}
+	if _, c := findItem(mappingValue(item, "capabilities"), "name", name); c != nil {
+		return c, nil
+	}
+	return nil, fmt.Errorf("ошибка: возможность %q не найдена у провайдера %q", name, provider)
+}
+
+// lookup возвращает значение ключа верхнего уровня или nil.
+func (d *Document) lookup(key string) *yaml.Node {
+	return mappingValue(d.root.Content[0], key)
+}
+
+// section возвращает список верхнего уровня key, создавая его при необходимости.
+func (d *Document) section(key string) (*yaml.Node, error) {
+	return d.ensureKey(d.root.Content[0], key, yaml.SequenceNode)
+}
+
+// ensureKey — ensureKey с позицией значения в ошибке.
+func (d *Document) ensureKey(m *yaml.Node, key string, kind yaml.Kind) (*yaml.Node, error) {
+	v, err := ensureKey(m, key, kind)
+	if err != nil {
+		return nil, positionError(d.fileOf(v), v, err)
+	}
+	return v, nil
+}
+
+// ensureKey возвращает значение ключа key отображения m; если ключа нет
+// или он пуст, создаёт значение вида kind. Если значение другого вида,
+// оно возвращается вместе с ошибкой без позиции, а дерево не меняется.
+func ensureKey(m *yaml.Node, key string, kind yaml.Kind) (*yaml.Node, error) {
+	m = resolveAlias(m)
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value != key {
+			continue
+		}
+		v := resolveAlias(m.Content[i+1])
+		if v.Kind == yaml.ScalarNode && v.ShortTag() == "!!null" {
+			v = &yaml.Node{Kind: kind, Tag: kindTag(kind)}
+			m.Content[i+1] = v
+		}
+		if v.Kind != kind {
+			return v, fmt.Errorf("%s должен быть %s", key, kindName(kind))
+		}
+		return v, nil
+	}
+
+	v := &yaml.Node{Kind: kind, Tag: kindTag(kind)}
+	m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, v)
+	return v, nil
+}
+
+func kindTag(kind yaml.Kind) string {
+	if kind == yaml.SequenceNode {
+		return "!!seq"
+	}
+	return "!!map"
+}
+
+func kindName(kind yaml.Kind) string {
+	if kind == yaml.SequenceNode {
+		return "списком"
+	}
+	return "отображением"
+}
+
+// findItem ищет элемент списка seq, у которого поле key равно name.
+func findItem(seq *yaml.Node, key, name string) (int, *yaml.Node) {
+	seq = resolveAlias(seq)
+	if seq == nil || seq.Kind != yaml.SequenceNode {
+		return -1, nil
+	}
+	for i, item := range seq.Content {
+		if scalarValue(mappingValue(item, key)) == name {
+			return i, resolveAlias(item)
+		}
+	}
+	return -1, nil
+}
+
+// removeItem удаляет элемент списка seq, у которого поле key равно name.
+func removeItem(seq *yaml.Node, key, name string) bool {
+	i, item := findItem(seq, key, name)
+	if item == nil {
+		return false
+	}
+	seq = resolveAlias(seq)
+	seq.Content = append(seq.Content[:i], seq.Content[i+1:]...)
+	return true
+}
+
+func indexOfScalar(seq *yaml.Node, value string) int {
+	if seq == nil || seq.Kind != yaml.SequenceNode {
+		return -1
+	}
+	for i, n := range seq.Content {
+		if scalarValue(n) == value {
+			return i
+		}
+	}
+	return -1
+}
+
+// appendItem кодирует v и добавляет его в конец списка seq.
+func appendItem(seq *yaml.Node, v interface{}) error {
+	if seq.Kind != yaml.SequenceNode {
+		return positionError("", seq, errors.New("элемент можно добавить только в список"))
+	}
+	n, err := encodeNode(v)
+	if err != nil {
+		return err
+	}
+	seq.Content = append(seq.Content, n)
+	return nil
+}
+
+// updateItem декодирует item в v, вызывает fn и переносит изменения
+// обратно в item.
+func updateItem(item *yaml.Node, v interface{}, fn func() error) error {
+	if err := item.Decode(v); err != nil {
+		return fmt.Errorf("ошибка при чтении элемента в строке %d: %w", item.Line, err)
+	}
+	if err := fn(); err != nil {
+		return err
+	}
+
+	value := reflect.ValueOf(v).Elem().Interface()
+	switch x := value.(type) {
+	case Provider:
+		value = exactProvider(x)
+	case Resource:
+		value = exactResource(x)
+	case Capability:
+		value = exactCapability(x)
+	}
+	src, err := encodeNode(value)
+	if err != nil {
+		return err
+	}
+	mergeNode(item, src, reflect.TypeOf(v).Elem())
+	return nil
+}
+
+// encodeNode кодирует значение в узел, опуская пустые поля структур.
+func encodeNode(v interface{}) (*yaml.Node, error) {
+	var n yaml.Node
+	if err := n.Encode(v); err != nil {
+		return nil, fmt.Errorf("ошибка при генерации YAML: %w", err)
+	}
+	pruneNode(&n, reflect.TypeOf(v))
+	return &n, nil
+}
+
+// pruneNode удаляет из отображений, соответствующих структурам типа t,
+// ключи с пустыми значениями. Содержимое карт (например, properties)
+// не трогается: там false и 0 — осмысленные значения.
+func pruneNode(n *yaml.Node, t reflect.Type) {
+	if t == nil {
+		return
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if n.Kind != yaml.MappingNode {
+			return
+		}
+		content := n.Content[:0]
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k, v := n.Content[i], n.Content[i+1]
+			if f, ok := yamlFieldByKey(t, k.Value); ok {
+				pruneNode(v, f.Type)
+				if isEmptyNode(v) {
+					continue
+				}
+			}
+			content = append(content, k, v)
+		}
+		n.Content = content
+	case reflect.Slice, reflect.Array:
+		for _, c := range n.Content {
+			pruneNode(c, t.Elem())
+		}
+	case reflect.Map:
+		for i := 1; i < len(n.Content); i += 2 {
+			pruneNode(n.Content[i], t.Elem())
+		}
+	}
+}
+
+// isEmptyNode сообщает, соответствует ли узел нулевому значению.
+func isEmptyNode(n *yaml.Node) bool {
+	n = resolveAlias(n)
+	if n == nil {
+		return true
+	}
+	switch n.Kind {
+	case yaml.MappingNode, yaml.SequenceNode:
+		return len(n.Content) == 0
+	case yaml.ScalarNode:
+		switch n.ShortTag() {
+		case "!!null":
+			return true
+		case "!!str":
+			return n.Value == ""
+		case "!!int", "!!float":
+			return n.Value == "0" || n.Value == "0.0"
+		case "!!bool":
+			return n.Value == "false"
+		}
+	}
+	return false
+}
+
+// sameValue сообщает, кодируют ли два узла одно и то же значение.
+func sameValue(a, b *yaml.Node) bool {
+	var va, vb interface{}
+	if a.Decode(&va) != nil || b.Decode(&vb) != nil {
+		return false
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+// mergeNode переносит значение src в dst. Там, где значение не изменилось,
+// dst остаётся нетронутым вместе с комментариями, стилем и якорями.
+// t — тип Go, которому соответствует узел: для структур ключи, неизвестные
+// типу, сохраняются, а для карт лишние ключи удаляются.
+func mergeNode(dst, src *yaml.Node, t reflect.Type) {
+	if sameValue(dst, src) {
+		return
+	}
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if dst.Kind == yaml.AliasNode || dst.Kind != src.Kind {
+		replaceNode(dst, src)
+		return
+	}
+
+	switch dst.Kind {
+	case yaml.ScalarNode:
+		if dst.ShortTag() != "!!str" || src.ShortTag() != "!!str" {
+			dst.Style = src.Style
+		}
+		dst.Tag = src.Tag
+		dst.Value = src.Value
+	case yaml.MappingNode:
+		if t != nil && t.Kind() == reflect.Struct {
+			mergeStruct(dst, src, t)
+		} else {
+			var elem reflect.Type
+			if t != nil && t.Kind() == reflect.Map {
+				elem = t.Elem()
+			}
+			mergeMap(dst, src, elem)
+		}
+	case yaml.SequenceNode:
+		var elem reflect.Type
+		if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+			elem = t.Elem()
+		}
+		mergeSequence(dst, src, elem)
+	default:
+		replaceNode(dst, src)
+	}
+}
+
+func mergeStruct(dst, src *yaml.Node, t reflect.Type) {
+	content := dst.Content[:0:0]
+	for i := 0; i+1 < len(dst.Content); i += 2 {
+		k, v := dst.Content[i], dst.Content[i+1]
+		f, known := yamlFieldByKey(t, k.Value)
+		if !known {
+			content = append(content, k, v)
+			continue
+		}
+		if sv := mappingValue(src, k.Value); sv != nil {
+			mergeNode(v, sv, f.Type)
+			content = append(content, k, v)
+			continue
+		}
+		// В src пустые значения опущены: сохраняем ключ, только если
+		// в документе он тоже пуст (например, parameters: []).
+		if isEmptyNode(v) {
+			content = append(content, k, v)
+		}
+	}
+	dst.Content = appendMissingKeys(content, dst, src)
+}
+
+func mergeMap(dst, src *yaml.Node, elem reflect.Type) {
+	content := dst.Content[:0:0]
+	for i := 0; i+1 < len(dst.Content); i += 2 {
+		k, v := dst.Content[i], dst.Content[i+1]
+		if sv := mappingValue(src, k.Value); sv != nil {
+			mergeNode(v, sv, elem)
+			content = append(content, k, v)
+		}
+	}
+	dst.Content = appendMissingKeys(content, dst, src)
+}
+
+// appendMissingKeys добавляет к content ключи src, которых нет в dst.
+func appendMissingKeys(content []*yaml.Node, dst, src *yaml.Node) []*yaml.Node {
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		if mappingValue(dst, src.Content[i].Value) == nil {
+			content = append(content, src.Content[i], src.Content[i+1])
+		}
+	}
+	return content
+}
+
+// mergeSequence сопоставляет элементы-отображения по полю name, а прочие
+// элементы — по индексу.
+func mergeSequence(dst, src *yaml.Node, elem reflect.Type) {
+	if namedItems(dst) && namedItems(src) {
+		content := make([]*yaml.Node, 0, len(src.Content))
+		for _, s := range src.Content {
+			name := scalarValue(mappingValue(s, "name"))
+			if _, d := findItem(dst, "name", name); d != nil {
+				mergeNode(d, s, elem)
+				content = append(content, d)
+			} else {
+				content = append(content, s)
+			}
+		}
+		dst.Content = content
+		return
+	}
+
+	for i, s := range src.Content {
+		if i < len(dst.Content) {
+			mergeNode(dst.Content[i], s, elem)
+		} else {
+			dst.Content = append(dst.Content, s)
+		}
+	}
+	if len(dst.Content) > len(src.Content) {
+		dst.Content = dst.Content[:len(src.Content)]
+	}
+}
+
+// namedItems сообщает, что все элементы списка — отображения с полем name.
+func namedItems(seq *yaml.Node) bool {
+	for _, item := range seq.Content {
+		if scalarValue(mappingValue(item, "name")) == "" {
+			return false
+		}
+	}
+	return len(seq.Content) > 0
+}
+
+// replaceNode заменяет содержимое dst на src, сохраняя комментарии
+// и якорь dst: на узел могут ссылаться алиасы.
+func replaceNode(dst, src *yaml.Node) {
+	head, line, foot, anchor := dst.HeadComment, dst.LineComment, dst.FootComment, dst.Anchor
+	*dst = *src
+	if dst.Kind == yaml.AliasNode {
+		anchor = ""
+	}
+	dst.Anchor = anchor
+	if dst.HeadComment == "" {
+		dst.HeadComment = head
+	}
+	if dst.LineComment == "" {
+		dst.LineComment = line
+	}
+	if dst.FootComment == "" {
+		dst.FootComment = foot
+	}
+}
@@ -0,0 +1,189 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+const commentedYAML = `# Инфраструктура разработки
+openinfra: 1.0.0
+info:
+  title: Dev # краткое название
+
+x-owner: platform-team
+
+defaults: &auth
+  method: password
+  username: admin
+
+providers:
+  # локальный гипервизор
+  - name: vbox
+    type: virtualbox
+    connection:
+      protocol: ssh
+      host: "192.168.1.10" # адрес в лаборатории
+      authentication: *auth
+    capabilities:
+      - name: start_vm
+        method: POST
+        endpoint: /vms/{vm_id}/start
+        x-internal: true
+      - name: stop_vm
+        method: POST
+        endpoint: /vms/{vm_id}/stop
+
+components:
+  - name: vm
+    type: virtual_machine
+    provider: vbox
+    properties:
+      cpu: 2 # ядра
+      memory: 4GB
+      enabled: false
+
+dependencies:
+  - component: vm
+    depends_on:
+      - net
+`
+
+func parseCommented(t *testing.T) *Document {
+	t.Helper()
+	doc, err := ParseDocument([]byte(commentedYAML))
+	require.NoError(t, err)
+	return doc
+}
+
+func documentText(t *testing.T, doc *Document) string {
+	t.Helper()
+	out, err := doc.Bytes()
+	require.NoError(t, err)
+	return string(out)
+}
+
+func TestDocumentUnchanged(t *testing.T) {
+	out := documentText(t, parseCommented(t))
+	assert.Contains(t, out, "# Инфраструктура разработки")
+	assert.Contains(t, out, "# локальный гипервизор")
+	assert.Contains(t, out, "authentication: *auth")
+	assert.Contains(t, out, `host: "192.168.1.10" # адрес в лаборатории`)
+}
+
+func TestDocumentUpdateProvider(t *testing.T) {
+	doc := parseCommented(t)
+
+	err := doc.UpdateProvider("vbox", func(p *Provider) error {
+		p.Connection.Port = 2222
+		p.Capabilities[1].Description = "Stop a virtual machine"
+		return nil
+	})
+	require.NoError(t, err)
+
+	out := documentText(t, doc)
+	assert.Contains(t, out, "# локальный гипервизор\n  - name: vbox")
+	assert.Contains(t, out, `host: "192.168.1.10" # адрес в лаборатории`)
+	assert.Contains(t, out, "authentication: *auth\n      port: 2222\n")
+	assert.Contains(t, out, "x-internal: true")
+	assert.Contains(t, out, "endpoint: /vms/{vm_id}/stop\n        description: Stop a virtual machine\n")
+	assert.Contains(t, out, "x-owner: platform-team")
+
+	spec, err := doc.Spec()
+	require.NoError(t, err)
+	assert.Equal(t, 2222, spec.Providers["vbox"].Connection.Port)
+	assert.Equal(t, "admin", spec.Providers["vbox"].Connection.Authentication.Username)
+}
+
+func TestDocumentUpdateComponentProperties(t *testing.T) {
+	doc := parseCommented(t)
+
+	err := doc.UpdateComponent("vm", func(r *Resource) error {
+		r.Properties["memory"] = "8GB"
+		r.Properties["ratio"] = 1.0
+		delete(r.Properties, "enabled")
+		return nil
+	})
+	require.NoError(t, err)
+
+	out := documentText(t, doc)
+	assert.Contains(t, out, "    properties:\n      cpu: 2 # ядра\n      memory: 8GB\n      ratio: 1.0\n")
+	assert.NotContains(t, out, "enabled")
+}
+
+func TestDocumentAddRemove(t *testing.T) {
+	doc := parseCommented(t)
+
+	require.NoError(t, doc.AddProvider(Provider{Name: "aws", Type: "cloud", Connection: Connection{Protocol: "https"}}))
+	assert.EqualError(t, doc.AddProvider(Provider{Name: "aws"}), `ошибка: провайдер "aws" уже существует`)
+
+	require.NoError(t, doc.AddComponent(Resource{Name: "net", Type: "network", Provider: "aws",
+		Properties: map[string]interface{}{"public": false}}))
+	require.NoError(t, doc.AddCapability("vbox", Capability{Name: "restart_vm", Method: "POST", Endpoint: "/vms/{vm_id}/restart"}))
+	require.NoError(t, doc.RemoveCapability("vbox", "stop_vm"))
+	require.NoError(t, doc.UpdateCapability("vbox", "start_vm", func(c *Capability) error {
+		c.Method = "PUT"
+		return nil
+	}))
+	require.NoError(t, doc.AddDependency("vm", "net", "dns"))
+	require.NoError(t, doc.AddDependency("net", "dns"))
+	require.NoError(t, doc.RemoveDependency("net", "dns"))
+
+	out := documentText(t, doc)
+	assert.Contains(t, out, "  - name: aws\n    type: cloud\n    connection:\n      protocol: https\n")
+	assert.Contains(t, out, "  - type: network\n    provider: aws\n    name: net\n    properties:\n      public: false\n")
+	assert.Contains(t, out, "depends_on:\n      - net\n      - dns\n")
+	assert.NotContains(t, out, "stop_vm")
+	assert.NotContains(t, out, "component: net")
+
+	spec, err := doc.Spec()
+	require.NoError(t, err)
+	caps := spec.ProviderCapabilityList("vbox")
+	require.Len(t, caps, 2)
+	assert.Equal(t, "PUT", caps[0].Method)
+	assert.Equal(t, "restart_vm", caps[1].Name)
+
+	require.NoError(t, doc.RemoveComponent("net"))
+	require.NoError(t, doc.RemoveProvider("aws"))
+	assert.EqualError(t, doc.RemoveProvider("aws"), `ошибка: провайдер "aws" не найден`)
+	assert.EqualError(t, doc.RemoveDependency("vm", "ghost"), `ошибка: зависимость "vm" от "ghost" не найдена`)
+}
+
+func TestDocumentEmpty(t *testing.T) {
+	doc, err := ParseDocument(nil)
+	require.NoError(t, err)
+
+	require.NoError(t, doc.AddProvider(Provider{Name: "aws", Type: "cloud"}))
+	assert.Equal(t, "providers:\n  - name: aws\n    type: cloud\n", documentText(t, doc))
+
+	_, err = ParseDocument([]byte("- just\n- a list\n"))
+	assert.Error(t, err)
+}
+
+func TestDocumentSectionOfWrongKind(t *testing.T) {
+	const text = `openinfra: 1.1.0
+providers:
+  aws:
+    type: cloud
+  vbox:
+    type: hypervisor
+dependencies:
+  - component: vm
+    depends_on: net
+`
+	doc, err := ParseDocument([]byte(text), WithSource("spec.yaml"))
+	require.NoError(t, err)
+
+	assert.EqualError(t, doc.AddProvider(Provider{Name: "kvm"}),
+		"ошибка в файле spec.yaml:3:3: providers должен быть списком")
+	assert.EqualError(t, doc.AddDependency("vm", "dns"),
+		"ошибка в файле spec.yaml:9:17: depends_on должен быть списком")
+
+	// Документ не изменился
+	assert.Equal(t, text, documentText(t, doc))
+
+	assert.EqualError(t, appendItem(&yaml.Node{Kind: yaml.MappingNode, Line: 2, Column: 3}, Provider{}),
+		"ошибка: 2:3: элемент можно добавить только в список")
+}
@@ -0,0 +1,270 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ExecOption настраивает выполнение возможности провайдера.
+type ExecOption func(*execConfig)
+
+type execConfig struct {
+	client       *http.Client
+	transport    http.RoundTripper
+	timeout      time.Duration
+	localSecrets bool
+}
+
+// WithHTTPClient задаёт HTTP-клиент для запросов к провайдеру,
+// например с настроенным прокси или mTLS.
+func WithHTTPClient(client *http.Client) ExecOption {
+	return func(c *execConfig) {
+		c.client = client
+	}
+}
+
+// WithTransport задаёт RoundTripper для запросов к провайдеру.
+// Игнорируется, если клиент задан через WithHTTPClient.
+func WithTransport(rt http.RoundTripper) ExecOption {
+	return func(c *execConfig) {
+		c.transport = rt
+	}
+}
+
+// WithLocalSecrets разрешает ссылкам на секреты ${env:NAME} и ${file:path}
+// читать переменные окружения и файлы этой машины. По умолчанию они
+// выключены: спецификация сама выбирает и ссылку, и адрес, на который
+// уйдёт значение, поэтому включайте их только для доверенных спецификаций.
+func WithLocalSecrets() ExecOption {
+	return func(c *execConfig) {
+		c.localSecrets = true
+	}
+}
+
+// WithTimeout задаёт таймаут для возможностей, у которых в спецификации
+// не указан собственный timeout.
+func WithTimeout(d time.Duration) ExecOption {
+	return func(c *execConfig) {
+		c.timeout = d
+	}
+}
+
+func newExecConfig(opts []ExecOption) *execConfig {
+	c := &execConfig{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// httpClientKey — ключ контекста запроса с HTTP-клиентом выполнения.
+type httpClientKey struct{}
+
+// HTTPClientFromContext возвращает HTTP-клиент, которым выполняется
+// запрос с контекстом ctx (см. WithHTTPClient и WithTransport), или
+// http.DefaultClient. Пригодится аутентификаторам, которые сами
+// обращаются к серверу, например за токеном.
+func HTTPClientFromContext(ctx context.Context) *http.Client {
+	if client, ok := ctx.Value(httpClientKey{}).(*http.Client); ok {
+		return client
+	}
+	return http.DefaultClient
+}
+
+func (c *execConfig) httpClient() *http.Client {
+	switch {
+	case c.client != nil:
+		return c.client
+	case c.transport != nil:
+		return &http.Client{Transport: c.transport}
+	}
+	return http.DefaultClient
+}
+
+// ExecuteCapability выполняет возможность провайдера name с параметрами params
+// и возвращает тело ответа. Статус вне 2xx возвращается как *CapabilityError.
+func (p *Provider) ExecuteCapability(name string, params map[string]interface{}) (string, error) {
+	result, err := p.ExecuteCapabilityContext(context.Background(), name, params)
+	if result == nil {
+		return "", err
+	}
+	return result.String(), err
+}
+
+// ExecuteCapabilityContext выполняет возможность провайдера с учётом контекста:
+// запрос прерывается при отмене ctx или по истечении таймаута возможности.
+// Если провайдер ответил статусом вне 2xx, возвращается и результат,
+// и ошибка *CapabilityError.
+func (p *Provider) ExecuteCapabilityContext(ctx context.Context, name string, params map[string]interface{}, opts ...ExecOption) (*CapabilityResult, error) {
+	cfg := newExecConfig(opts)
+
+	for _, capability := range p.Capabilities {
+		if capability.Name == name {
+			timeout := capability.Timeout
+			if timeout == 0 {
+				timeout = cfg.timeout
+			}
+			if timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, timeout)
+				defer cancel()
+			}
+
+			params, err := ValidateParameters(capability.Parameters, params)
+			if err != nil {
+				return nil, err
+			}
+
+			client := cfg.httpClient()
+			req, err := p.newRequest(context.WithValue(ctx, httpClientKey{}, client), capability, params)
+			if err != nil {
+				return nil, err
+			}
+
+			// Аутентификация
+			authenticator, err := LookupAuthenticator(p.Connection.Authentication.Method)
+			if err != nil {
+				return nil, err
+			}
+			if cfg.localSecrets {
+				ctx = LocalSecretsContext(ctx)
+			}
+			auth, err := p.Connection.Authentication.Resolve(ctx)
+			if err != nil {
+				return nil, err
+			}
+			if err := authenticator.Authenticate(req, auth); err != nil {
+				return nil, fmt.Errorf("ошибка аутентификации (%s): %w", auth.Method, err)
+			}
+
+			resp, err := client.Do(req)
+			if err != nil {
+				return nil, fmt.Errorf("ошибка при выполнении запроса: %w", err)
+			}
+			defer resp.Body.Close()
+
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				return nil, fmt.Errorf("ошибка при чтении ответа: %w", err)
+			}
+
+			result := &CapabilityResult{
+				StatusCode: resp.StatusCode,
+				Header:     resp.Header,
+				Body:       body,
+			}
+			if resp.StatusCode < 200 || resp.StatusCode > 299 {
+				return result, &CapabilityError{
+					Provider:   p.Name,
+					Capability: name,
+					StatusCode: resp.StatusCode,
+					Status:     resp.Status,
+					Body:       body,
+				}
+			}
+			if err := result.decodeBody(capability.Response); err != nil {
+				return result, fmt.Errorf("возможность %s провайдера %s: %w", name, p.Name, err)
+			}
+			return result, nil
+		}
+	}
+	return nil, fmt.Errorf("возможность %s не найдена у провайдера %s", name, p.Name)
+}
+
+// newRequest формирует HTTP-запрос для возможности: подставляет параметры
+// пути, добавляет параметры запроса и заголовки и собирает JSON-тело.
+// Параметры должны быть уже проверены ValidateParameters.
+func (p *Provider) newRequest(ctx context.Context, capability Capability, params map[string]interface{}) (*http.Request, error) {
+	// Формируем URL
+	endpoint := p.Connection.Host
+	if p.Connection.Endpoint != "" {
+		endpoint = p.Connection.Endpoint
+	}
+	endpoint += capability.Endpoint
+	path := capability.Endpoint
+
+	query := url.Values{}
+	header := http.Header{}
+	var body map[string]interface{}
+
+	for _, param := range capability.Parameters {
+		value, exists := params[param.Name]
+		if !exists {
+			continue
+		}
+
+		switch param.location(capability.Method, path) {
+		case ParamInPath:
+			endpoint = strings.Replace(endpoint, "{"+param.Name+"}", url.PathEscape(fmt.Sprintf("%v", value)), -1)
+		case ParamInQuery:
+			if list, ok := value.([]interface{}); ok {
+				for _, v := range list {
+					query.Add(param.Name, fmt.Sprintf("%v", v))
+				}
+			} else {
+				query.Add(param.Name, fmt.Sprintf("%v", value))
+			}
+		case ParamInHeader:
+			header.Set(param.Name, fmt.Sprintf("%v", value))
+		case ParamInBody:
+			if body == nil {
+				body = make(map[string]interface{})
+			}
+			body[param.Name] = value
+		default:
+			return nil, fmt.Errorf("ошибка: неизвестное расположение %q параметра %s", param.In, param.Name)
+		}
+	}
+
+	if len(query) > 0 {
+		sep := "?"
+		if strings.Contains(endpoint, "?") {
+			sep = "&"
+		}
+		endpoint += sep + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка при формировании тела запроса: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	// Делаем HTTP-запрос
+	req, err := http.NewRequestWithContext(ctx, capability.Method, endpoint, reader)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при создании запроса: %w", err)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return req, nil
+}
+
+// location возвращает расположение параметра с учётом значения по умолчанию.
+func (param Parameter) location(method, path string) string {
+	if param.In != "" {
+		return param.In
+	}
+	if strings.Contains(path, "{"+param.Name+"}") {
+		return ParamInPath
+	}
+	switch strings.ToUpper(method) {
+	case "", http.MethodGet, http.MethodHead, http.MethodDelete:
+		return ParamInQuery
+	}
+	return ParamInBody
+}
@@ -0,0 +1,153 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// roundTripFunc позволяет подменить транспорт функцией.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestExecuteCapabilityContextTimeout(t *testing.T) {
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	provider := Provider{
+		Name:       "slow",
+		Connection: Connection{Endpoint: ts.URL},
+		Capabilities: []Capability{
+			{Name: "hang", Method: "GET", Endpoint: "/hang", Timeout: 50 * time.Millisecond},
+			{Name: "hang_default", Method: "GET", Endpoint: "/hang"},
+		},
+	}
+
+	_, err := provider.ExecuteCapabilityContext(context.Background(), "hang", nil)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	_, err = provider.ExecuteCapabilityContext(context.Background(), "hang_default", nil,
+		WithTimeout(50*time.Millisecond))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = provider.ExecuteCapabilityContext(ctx, "hang_default", nil)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestExecuteCapabilityContextTransport(t *testing.T) {
+	var got *http.Request
+	rt := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		got = r
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader("from transport")),
+			Header:     make(http.Header),
+		}, nil
+	})
+
+	provider := Provider{
+		Name:       "fake",
+		Connection: Connection{Endpoint: "http://provider.invalid"},
+		Capabilities: []Capability{
+			{Name: "list", Method: "GET", Endpoint: "/vms"},
+		},
+	}
+
+	result, err := provider.ExecuteCapabilityContext(context.Background(), "list", nil, WithTransport(rt))
+	assert.NoError(t, err)
+	assert.Equal(t, "from transport", result.String())
+	assert.Equal(t, "http://provider.invalid/vms", got.URL.String())
+
+	failing := &http.Client{Transport: roundTripFunc(func(*http.Request) (*http.Response, error) {
+		return nil, errors.New("нет соединения")
+	})}
+	_, err = provider.ExecuteCapabilityContext(context.Background(), "list", nil,
+		WithHTTPClient(failing), WithTransport(rt))
+	assert.ErrorContains(t, err, "нет соединения")
+}
+
+func TestCapabilityTimeoutYAML(t *testing.T) {
+	spec, err := ParseBytes([]byte(`
+providers:
+  - name: p
+    capabilities:
+      - name: slow
+        timeout: 1m30s
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, 90*time.Second, spec.Providers["p"].Capabilities[0].Timeout)
+}
+
+func TestExecuteCapabilityParameterLocations(t *testing.T) {
+	var got *http.Request
+	var gotBody string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		got, gotBody = r, string(data)
+	}))
+	defer ts.Close()
+
+	provider := Provider{
+		Name:       "vbox",
+		Connection: Connection{Endpoint: ts.URL},
+		Capabilities: []Capability{
+			{Name: "create_vm", Method: "POST", Endpoint: "/pools/{pool}/vms", Parameters: []Parameter{
+				{Name: "pool", Type: "string", Required: true},
+				{Name: "cpu", Type: "integer", Required: true},
+				{Name: "memory", Type: "string"},
+				{Name: "dry_run", Type: "boolean", In: ParamInQuery},
+				{Name: "X-Trace", Type: "string", In: ParamInHeader},
+				{Name: "tags", Type: "array"},
+			}},
+			{Name: "list_vms", Method: "GET", Endpoint: "/vms", Parameters: []Parameter{
+				{Name: "state", Type: "string"},
+				{Name: "id", Type: "array"},
+			}},
+		},
+	}
+
+	_, err := provider.ExecuteCapabilityContext(context.Background(), "create_vm", map[string]interface{}{
+		"pool":    "dev/eu 1",
+		"cpu":     2,
+		"dry_run": true,
+		"X-Trace": "abc",
+		"unused":  "ignored",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "/pools/dev%2Feu%201/vms", got.URL.EscapedPath())
+	assert.Equal(t, "dry_run=true", got.URL.RawQuery)
+	assert.Equal(t, "abc", got.Header.Get("X-Trace"))
+	assert.Equal(t, "application/json", got.Header.Get("Content-Type"))
+	assert.JSONEq(t, `{"cpu": 2}`, gotBody)
+
+	_, err = provider.ExecuteCapabilityContext(context.Background(), "list_vms", map[string]interface{}{
+		"state": "running",
+		"id":    []interface{}{"a", "b"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "GET", got.Method)
+	assert.Equal(t, "id=a&id=b&state=running", got.URL.RawQuery)
+	assert.Empty(t, gotBody)
+
+	_, err = provider.ExecuteCapabilityContext(context.Background(), "create_vm", map[string]interface{}{"pool": "x"})
+	assert.EqualError(t, err, "отсутствует обязательный параметр: cpu")
+}
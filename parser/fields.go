@@ -0,0 +1,116 @@
+package parser
+
+import (
+	"reflect"
+	"strings"
+)
+
+// yamlField описывает поле структуры так, как его видит yaml.v3.
+type yamlField struct {
+	Key string
+	// Index — путь к полю для FieldByIndex: у полей встроенных
+	// (yaml:",inline") структур в нём больше одного индекса
+	Index     []int
+	Type      reflect.Type
+	OmitEmpty bool
+}
+
+// yamlFields возвращает поля структуры t в порядке объявления, пропуская
+// неэкспортируемые поля и поля с тегом yaml:"-". Поля встроенных
+// структур (yaml:",inline") возвращаются на месте встраивания.
+func yamlFields(t reflect.Type) []yamlField {
+	t = structType(t)
+	if t == nil {
+		return nil
+	}
+
+	var fields []yamlField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		// Как и yaml.v3, встроенные структуры неэкспортируемых типов
+		// не пропускаются: их поля могут быть встроены через inline
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+		tag := f.Tag.Get("yaml")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if hasTagOption(opts, "inline") {
+			// Ключи встроенного отображения заранее неизвестны (см. inlineMap)
+			for _, inner := range yamlFields(f.Type) {
+				inner.Index = append([]int{i}, inner.Index...)
+				fields = append(fields, inner)
+			}
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		fields = append(fields, yamlField{
+			Key:       name,
+			Index:     []int{i},
+			Type:      f.Type,
+			OmitEmpty: hasTagOption(opts, "omitempty"),
+		})
+	}
+	return fields
+}
+
+// yamlFieldByKey ищет поле структуры t по ключу YAML. Если у структуры
+// есть встроенное отображение, в него попадает любой другой ключ:
+// тогда возвращается поле отображения с типом его значений.
+func yamlFieldByKey(t reflect.Type, key string) (yamlField, bool) {
+	for _, f := range yamlFields(t) {
+		if f.Key == key {
+			return f, true
+		}
+	}
+	if f, exists := inlineMap(t); exists {
+		f.Key = key
+		f.Type = f.Type.Elem()
+		return f, true
+	}
+	return yamlField{}, false
+}
+
+// inlineMap возвращает встроенное (yaml:",inline") отображение структуры t,
+// в том числе из встроенных структур.
+func inlineMap(t reflect.Type) (yamlField, bool) {
+	t = structType(t)
+	if t == nil {
+		return yamlField{}, false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		_, opts, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if (f.PkgPath != "" && !f.Anonymous) || !hasTagOption(opts, "inline") {
+			continue
+		}
+		if f.Type.Kind() == reflect.Map {
+			return yamlField{Index: []int{i}, Type: f.Type}, true
+		}
+		if inner, exists := inlineMap(f.Type); exists {
+			inner.Index = append([]int{i}, inner.Index...)
+			return inner, true
+		}
+	}
+	return yamlField{}, false
+}
+
+// structType возвращает структуру t без указателей или nil, если t
+// не структура.
+func structType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	return t
+}
+
+func hasTagOption(opts, option string) bool {
+	return strings.Contains(","+opts+",", ","+option+",")
+}
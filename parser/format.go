@@ -0,0 +1,163 @@
+package parser
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Format приводит документ к каноническому виду: ключи объектов идут
+// в порядке полей types.go (неизвестные ключи, например x-расширения,
+// следуют за ними в исходном порядке), вложенные блоки записываются
+// блочным стилем с отступом в два пробела, лишние кавычки снимаются.
+// Порядок элементов списков и ключей в properties не меняется,
+// комментарии сохраняются. Каждый документ потока, разделённого ---,
+// форматируется отдельно; документы из одних комментариев остаются
+// как есть. Результат всегда записывается в YAML, в том числе для JSON.
+func Format(data []byte, opts ...Option) ([]byte, error) {
+	o := newOptions(opts)
+	docs, err := decodeDocuments(data)
+	if err != nil {
+		if len(docs) > 0 {
+			return nil, &DocumentError{Index: len(docs), Err: o.yamlError(err)}
+		}
+		return nil, o.yamlError(err)
+	}
+	if len(docs) == 0 {
+		docs = []*yaml.Node{{}}
+	}
+
+	var buf bytes.Buffer
+	for i, root := range docs {
+		if i > 0 {
+			buf.WriteString("---\n")
+		}
+		if len(docs) > 1 && isEmptyDocument(root) {
+			buf.WriteString(documentComments(root))
+			continue
+		}
+		out, err := formatDocument(root, o.source)
+		if err != nil {
+			if len(docs) > 1 {
+				return nil, &DocumentError{Index: i, Err: err}
+			}
+			return nil, err
+		}
+		buf.Write(out)
+	}
+	return buf.Bytes(), nil
+}
+
+func formatDocument(root *yaml.Node, source string) ([]byte, error) {
+	doc, err := newDocument(root, source)
+	if err != nil {
+		return nil, err
+	}
+	doc.Format()
+	return doc.Bytes()
+}
+
+// documentComments возвращает комментарии пустого документа root.
+func documentComments(root *yaml.Node) string {
+	comments := []string{root.HeadComment}
+	if n := resolveAlias(root); n != nil && n != root {
+		comments = append(comments, n.HeadComment, n.LineComment, n.FootComment)
+	}
+	comments = append(comments, root.FootComment)
+
+	var sb strings.Builder
+	for _, c := range comments {
+		if c != "" {
+			sb.WriteString(c + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// Format приводит дерево документа к каноническому виду, см. Format.
+func (d *Document) Format() {
+	formatNode(d.root, reflect.TypeOf(rawSpec{}))
+}
+
+// formatNode упорядочивает ключи узла n по полям типа t и нормализует
+// стиль. Для значений без известного типа (t == nil) меняется только стиль.
+func formatNode(n *yaml.Node, t reflect.Type) {
+	if n == nil || n.Kind == yaml.AliasNode {
+		// Узел под якорем форматируется там, где он объявлен
+		return
+	}
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch n.Kind {
+	case yaml.DocumentNode:
+		for _, c := range n.Content {
+			formatNode(c, t)
+		}
+		return
+	case yaml.ScalarNode:
+		// Строка без кавычек, которая прочиталась бы как другой тип
+		// (например "1.0" или "true"), при записи снова получит кавычки
+		n.Style &^= yaml.SingleQuotedStyle | yaml.DoubleQuotedStyle
+		return
+	}
+
+	n.Style &^= yaml.FlowStyle
+
+	switch {
+	case n.Kind == yaml.SequenceNode:
+		var elem reflect.Type
+		if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+			elem = t.Elem()
+		}
+		for _, item := range n.Content {
+			formatNode(item, elem)
+		}
+	case n.Kind == yaml.MappingNode && t != nil && t.Kind() == reflect.Struct:
+		reorderKeys(n, t)
+	case n.Kind == yaml.MappingNode:
+		var elem reflect.Type
+		if t != nil && t.Kind() == reflect.Map {
+			elem = t.Elem()
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			formatNode(n.Content[i], nil)
+			formatNode(n.Content[i+1], elem)
+		}
+	}
+}
+
+// reorderKeys переставляет пары ключ-значение отображения n в порядке
+// полей структуры t и форматирует ключи и значения.
+func reorderKeys(n *yaml.Node, t reflect.Type) {
+	fields := yamlFields(t)
+	rank := make(map[string]int, len(fields))
+	for i, f := range fields {
+		rank[f.Key] = i
+	}
+
+	known := make([][]*yaml.Node, len(fields))
+	var unknown []*yaml.Node
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, value := n.Content[i], n.Content[i+1]
+		formatNode(key, nil)
+		r, exists := rank[key.Value]
+		if !exists || known[r] != nil {
+			// Неизвестные и повторные ключи сохраняют исходный порядок
+			formatNode(value, nil)
+			unknown = append(unknown, key, value)
+			continue
+		}
+		formatNode(value, fields[r].Type)
+		known[r] = []*yaml.Node{key, value}
+	}
+
+	content := make([]*yaml.Node, 0, len(n.Content))
+	for _, pair := range known {
+		content = append(content, pair...)
+	}
+	n.Content = append(content, unknown...)
+}
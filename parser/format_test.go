@@ -0,0 +1,180 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormat(t *testing.T) {
+	in := `# Инфраструктура стенда
+
+components:
+    - provider: vbox   # основной гипервизор
+      name: vm
+      type: "virtual_machine"
+      x-owner: team
+      properties: {memory: "4GB", cpu: 2, version: "1.0", enabled: 'true'}
+      actions: [{method: POST, name: start}]
+info: {version: '1.0', title: "Стенд"}
+openinfra: "1.1.0"
+providers:
+- type: hypervisor
+  name: vbox
+  connection:
+    authentication: {password: x, method: password, username: u}
+    host: h
+`
+	want := `# Инфраструктура стенда
+
+openinfra: 1.1.0
+info:
+  title: Стенд
+  version: "1.0"
+providers:
+  - name: vbox
+    type: hypervisor
+    connection:
+      host: h
+      authentication:
+        method: password
+        username: u
+        password: x
+components:
+  - type: virtual_machine
+    provider: vbox # основной гипервизор
+    name: vm
+    properties:
+      memory: 4GB
+      cpu: 2
+      version: "1.0"
+      enabled: "true"
+    actions:
+      - name: start
+        method: POST
+    x-owner: team
+`
+	out, err := Format([]byte(in))
+	require.NoError(t, err)
+	assert.Equal(t, want, string(out))
+
+	// Повторное форматирование ничего не меняет
+	again, err := Format(out)
+	require.NoError(t, err)
+	assert.Equal(t, want, string(again))
+
+	// Форматирование не меняет смысл документа
+	before, err := ParseBytes([]byte(in))
+	require.NoError(t, err)
+	after, err := ParseBytes(out)
+	require.NoError(t, err)
+	assert.Equal(t, stripSource(before), stripSource(after))
+}
+
+func TestFormatGeneratedYAML(t *testing.T) {
+	spec, err := ParseBytes([]byte(sampleYAML))
+	require.NoError(t, err)
+	generated, err := GenerateYAML(spec)
+	require.NoError(t, err)
+
+	out, err := Format([]byte(generated))
+	require.NoError(t, err)
+	assert.Equal(t, generated, string(out))
+}
+
+func TestFormatErrors(t *testing.T) {
+	_, err := Format([]byte("a: [b"), WithSource("broken.yaml"))
+	assert.ErrorContains(t, err, "некорректное форматирование YAML в файле broken.yaml")
+
+	_, err = Format([]byte("openinfra: 1.1.0\n---\n[a, b]\n"), WithSource("envs.yaml"))
+	var docErr *DocumentError
+	require.ErrorAs(t, err, &docErr)
+	assert.Equal(t, 1, docErr.Index)
+}
+
+func TestFormatStream(t *testing.T) {
+	out, err := Format([]byte(`providers: [{type: t, name: p}]
+openinfra: 1.1.0
+---
+# Пустой документ
+---
+{"openinfra": "1.1.0", "info": {"version": "1.0", "title": "prod"}}
+`))
+	require.NoError(t, err)
+	assert.Equal(t, `openinfra: 1.1.0
+providers:
+  - name: p
+    type: t
+---
+# Пустой документ
+---
+openinfra: 1.1.0
+info:
+  title: prod
+  version: "1.0"
+`, string(out))
+
+	// Повторное форматирование ничего не меняет
+	again, err := Format(out)
+	require.NoError(t, err)
+	assert.Equal(t, string(out), string(again))
+}
+
+func TestFormatJSON(t *testing.T) {
+	out, err := Format([]byte(`{"openinfra": "1.1.0", "components": [{"name": "vm", "provider": "p"}]}`))
+	require.NoError(t, err)
+	assert.Equal(t, "openinfra: 1.1.0\ncomponents:\n  - provider: p\n    name: vm\n", string(out))
+}
+
+func TestFormatQuotedKeys(t *testing.T) {
+	out, err := Format([]byte(`'providers':
+  - "name": vbox
+    'type': hypervisor
+"openinfra": 1.1.0
+components:
+  - name: vm
+    provider: vbox
+    properties: {"cpu": 2, "true": yes, '1.0': v, "x-owner": team}
+`))
+	require.NoError(t, err)
+	assert.Equal(t, `openinfra: 1.1.0
+providers:
+  - name: vbox
+    type: hypervisor
+components:
+  - provider: vbox
+    name: vm
+    properties:
+      cpu: 2
+      "true": yes
+      "1.0": v
+      x-owner: team
+`, string(out))
+
+	// Ключ, который без кавычек прочитался бы иначе, остаётся строкой
+	spec, err := ParseBytes(out)
+	require.NoError(t, err)
+	assert.Equal(t, "v", spec.Resources["vm"].Properties["1.0"])
+}
+
+func TestFormatKeepsScalarValues(t *testing.T) {
+	for _, s := range trickyStrings {
+		doc, err := ParseDocument(nil)
+		require.NoError(t, err)
+		require.NoError(t, doc.AddComponent(Resource{
+			Name:       "c",
+			Type:       "t",
+			Provider:   "p",
+			Properties: map[string]interface{}{"value": s},
+		}))
+		data, err := doc.Bytes()
+		require.NoError(t, err)
+
+		out, err := Format(data)
+		require.NoError(t, err, s)
+		spec, err := ParseBytes(out)
+		require.NoError(t, err, s)
+		assert.Equal(t, s, spec.Resources["c"].Properties["value"], "значение %q", s)
+	}
+}
@@ -1,17 +1,150 @@
 package parser
 
 import (
+	"bytes"
 	"fmt"
+	"math"
+	"strconv"
 
 	"gopkg.in/yaml.v3"
 )
 
-// GenerateYAML генерирует YAML-строку из OpenInfraSpec
+// GenerateYAML генерирует YAML-строку из OpenInfraSpec.
+// Провайдеры и компоненты записываются списками, как их ожидает ParseFile:
+// в порядке исходного документа, а добавленные позже — по алфавиту.
 func GenerateYAML(spec *OpenInfraSpec) (string, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+
 	// Маршаллинг данных в YAML
-	data, err := yaml.Marshal(spec)
-	if err != nil {
+	if err := enc.Encode(spec.toRaw()); err != nil {
+		return "", fmt.Errorf("ошибка при генерации YAML: %w", err)
+	}
+	if err := enc.Close(); err != nil {
 		return "", fmt.Errorf("ошибка при генерации YAML: %w", err)
 	}
-	return string(data), nil
+	return buf.String(), nil
+}
+
+// MarshalYAML записывает спецификацию в каноническом виде со списками.
+func (ois OpenInfraSpec) MarshalYAML() (interface{}, error) {
+	return ois.toRaw(), nil
+}
+
+// UnmarshalYAML читает спецификацию так же, как ParseBytes без опций:
+// документ прежней версии приводится к текущей, выражения ${...}
+// подставляются, а у элементов заполняются позиции. Поэтому спецификацию
+// можно встроить в другой YAML-документ, например в конфигурацию
+// приложения. Узел node не изменяется.
+func (ois *OpenInfraSpec) UnmarshalYAML(node *yaml.Node) error {
+	spec, err := parseDocumentNode(copyNode(node, make(map[*yaml.Node]*yaml.Node)), newOptions(nil))
+	if err != nil {
+		return err
+	}
+	*ois = *spec
+	return nil
+}
+
+// toRaw преобразует карты провайдеров и компонентов обратно в списки.
+func (ois *OpenInfraSpec) toRaw() rawSpec {
+	raw := rawSpec{
+		Version:      ois.Version,
+		Info:         ois.Info,
+		Variables:    ois.Variables,
+		Dependencies: ois.Dependencies,
+	}
+
+	for _, name := range ois.providerNames() {
+		p := exactProvider(ois.Providers[name])
+		if p.Name == "" {
+			p.Name = name
+		}
+		raw.Providers = append(raw.Providers, p)
+	}
+	for _, name := range ois.resourceNames() {
+		r := exactResource(ois.Resources[name])
+		if r.Name == "" {
+			r.Name = name
+		}
+		raw.Resources = append(raw.Resources, r)
+	}
+	return raw
+}
+
+// exactProvider возвращает копию провайдера, подготовленную к записи в YAML.
+func exactProvider(p Provider) Provider {
+	if p.Capabilities != nil {
+		caps := make([]Capability, len(p.Capabilities))
+		for i, c := range p.Capabilities {
+			caps[i] = exactCapability(c)
+		}
+		p.Capabilities = caps
+	}
+	return p
+}
+
+// exactCapability возвращает копию возможности, подготовленную к записи в YAML.
+func exactCapability(c Capability) Capability {
+	c.Parameters = exactParameters(c.Parameters)
+	if c.Response != nil {
+		c.Response = &ResponseSchema{Type: c.Response.Type, Fields: exactParameters(c.Response.Fields)}
+	}
+	return c
+}
+
+// exactResource возвращает копию компонента, подготовленную к записи в YAML.
+func exactResource(r Resource) Resource {
+	if r.Properties != nil {
+		r.Properties = exactValue(r.Properties).(map[string]interface{})
+	}
+	return r
+}
+
+// exactParameters копирует параметры так, чтобы значения enum и default
+// сохранили свой тип при повторном разборе.
+func exactParameters(params []Parameter) []Parameter {
+	if params == nil {
+		return nil
+	}
+	out := make([]Parameter, len(params))
+	for i, p := range params {
+		if p.Enum != nil {
+			p.Enum = exactValue(p.Enum).([]interface{})
+		}
+		if p.Default != nil {
+			p.Default = exactValue(p.Default)
+		}
+		out[i] = p
+	}
+	return out
+}
+
+// exactValue копирует произвольное значение, заменяя дробные числа без
+// дробной части узлами вида 2.0: иначе yaml.v3 запишет их как 2,
+// и при разборе они превратятся в целые.
+func exactValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, e := range val {
+			out[k] = exactValue(e)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, e := range val {
+			out[i] = exactValue(e)
+		}
+		return out
+	case float64:
+		if val == math.Trunc(val) && !math.IsInf(val, 0) && math.Abs(val) < 1e21 {
+			return &yaml.Node{
+				Kind:  yaml.ScalarNode,
+				Tag:   "!!float",
+				Value: strconv.FormatFloat(val, 'f', 1, 64),
+			}
+		}
+	}
+	return v
 }
@@ -1 +1,292 @@
 package parser
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestGenerateYAMLEmitsLists(t *testing.T) {
+	spec, err := ParseBytes([]byte(sampleYAML))
+	require.NoError(t, err)
+
+	out, err := GenerateYAML(spec)
+	require.NoError(t, err)
+	assert.Contains(t, out, "providers:\n  - name: local_virtualbox\n")
+	assert.Contains(t, out, "components:\n  - type: virtual_machine\n    provider: local_virtualbox\n    name: local_vm\n")
+
+	again, err := ParseBytes([]byte(out))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"local_virtualbox", "cloud_provider"}, again.providerNames())
+	assert.Equal(t, []string{"local_vm", "local_network"}, again.resourceNames())
+	assert.Equal(t, stripSource(spec), stripSource(again))
+}
+
+func TestGenerateYAMLStableOrder(t *testing.T) {
+	spec := &OpenInfraSpec{
+		Version: "1.0.0",
+		Providers: map[string]Provider{
+			"zeta":  {Type: "cloud"},
+			"alpha": {Type: "cloud"},
+		},
+	}
+
+	out, err := GenerateYAML(spec)
+	require.NoError(t, err)
+	assert.Regexp(t, `(?s)- name: alpha.*- name: zeta`, out)
+
+	for i := 0; i < 10; i++ {
+		again, err := GenerateYAML(spec)
+		require.NoError(t, err)
+		assert.Equal(t, out, again)
+	}
+}
+
+func TestGenerateYAMLKeepsFloats(t *testing.T) {
+	spec := &OpenInfraSpec{Resources: map[string]Resource{
+		"vm": {Properties: map[string]interface{}{"ratio": 2.0, "cpu": 2, "list": []interface{}{1.0, 1.5}}},
+	}}
+
+	out, err := GenerateYAML(spec)
+	require.NoError(t, err)
+
+	parsed, err := ParseBytes([]byte(out))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"ratio": 2.0, "cpu": 2, "list": []interface{}{1.0, 1.5}},
+		parsed.Resources["vm"].Properties)
+}
+
+func TestUnmarshalYAMLMatchesParse(t *testing.T) {
+	for _, data := range []string{legacyYAML, sampleYAML} {
+		parsed, err := ParseBytes([]byte(data))
+		require.NoError(t, err)
+
+		var spec OpenInfraSpec
+		require.NoError(t, yaml.Unmarshal([]byte(data), &spec))
+		assert.Equal(t, stripSource(parsed), stripSource(&spec))
+		assert.Equal(t, parsed.Warnings(), spec.Warnings())
+	}
+
+	// Спецификация внутри другого документа: позиции указывают на него
+	var config struct {
+		Spec OpenInfraSpec `yaml:"spec"`
+	}
+	require.NoError(t, yaml.Unmarshal([]byte(`name: lab
+spec:
+  openinfra: 1.0.0
+  variables:
+    size: small
+  components:
+    - name: vm
+      actions: [start]
+      properties:
+        size: ${var.size}
+`), &config))
+	assert.Equal(t, map[string]interface{}{"size": "small"}, config.Spec.Resources["vm"].Properties)
+	assert.Equal(t, []Action{{Name: "start", Pos: Position{Line: 8, Column: 17}}}, config.Spec.Resources["vm"].Actions)
+	assert.Equal(t, Position{Line: 7, Column: 7}, config.Spec.Resources["vm"].Pos)
+
+	err := yaml.Unmarshal([]byte("openinfra: 2.0.0\n"), &config.Spec)
+	assert.EqualError(t, err, "ошибка в поле openinfra: версия 2.0.0 не поддерживается, поддерживаемые версии: 1.0, 1.1")
+}
+
+// TestGenerateYAMLRoundTrip проверяет на случайных спецификациях,
+// что parse → generate → parse не теряет данных.
+func TestGenerateYAMLRoundTrip(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 200; i++ {
+		initial, err := GenerateYAML(randomSpec(rnd))
+		require.NoError(t, err)
+
+		first, err := ParseBytes([]byte(initial))
+		require.NoError(t, err, initial)
+
+		generated, err := GenerateYAML(first)
+		require.NoError(t, err)
+
+		second, err := ParseBytes([]byte(generated))
+		require.NoError(t, err, generated)
+
+		regenerated, err := GenerateYAML(second)
+		require.NoError(t, err)
+
+		require.Equal(t, stripSource(first), stripSource(second), generated)
+		require.Equal(t, generated, regenerated)
+	}
+}
+
+// stripSource убирает сведения об источнике, которые различаются
+// у спецификаций, разобранных из разных текстов.
+func stripSource(spec *OpenInfraSpec) *OpenInfraSpec {
+	clone := *spec
+	clone.root = nil
+	clone.source = ""
+
+	// Позиции зависят от разметки документа, а не от его содержания
+	clone.Providers = make(map[string]Provider, len(spec.Providers))
+	for name, p := range spec.Providers {
+		p.Pos = Position{}
+		p.Capabilities = append([]Capability(nil), p.Capabilities...)
+		for i := range p.Capabilities {
+			p.Capabilities[i].Pos = Position{}
+		}
+		clone.Providers[name] = p
+	}
+	clone.Resources = make(map[string]Resource, len(spec.Resources))
+	for name, r := range spec.Resources {
+		r.Pos = Position{}
+		r.Actions = append([]Action(nil), r.Actions...)
+		for i := range r.Actions {
+			r.Actions[i].Pos = Position{}
+		}
+		r.Dependencies = stripDependencies(r.Dependencies)
+		clone.Resources[name] = r
+	}
+	clone.Dependencies = stripDependencies(spec.Dependencies)
+	return &clone
+}
+
+func stripDependencies(deps []Dependency) []Dependency {
+	deps = append([]Dependency(nil), deps...)
+	for i := range deps {
+		deps[i].Pos = Position{}
+	}
+	return deps
+}
+
+var trickyStrings = []string{
+	"", "plain", "yes", "no", "null", "~", "1.0", "0x10", "1e3", "true",
+	"a: b", "#comment", "- item", " leading", "trailing ", "multi\nline",
+	"quote'd", `double"quoted`, "привет", "${env:SECRET}", "{brace}", "[list]",
+}
+
+func randomString(rnd *rand.Rand) string {
+	if rnd.Intn(3) == 0 {
+		return trickyStrings[rnd.Intn(len(trickyStrings))]
+	}
+	return fmt.Sprintf("s%d", rnd.Intn(1000))
+}
+
+func randomValue(rnd *rand.Rand, depth int) interface{} {
+	n := 6
+	if depth > 1 {
+		n = 4
+	}
+	switch rnd.Intn(n) {
+	case 0:
+		return rnd.Intn(2000) - 1000
+	case 1:
+		if rnd.Intn(2) == 0 {
+			return float64(rnd.Intn(100))
+		}
+		return rnd.Float64() * 100
+	case 2:
+		return rnd.Intn(2) == 0
+	case 3:
+		return randomString(rnd)
+	case 4:
+		list := make([]interface{}, rnd.Intn(3))
+		for i := range list {
+			list[i] = randomValue(rnd, depth+1)
+		}
+		return list
+	default:
+		obj := make(map[string]interface{})
+		for i := rnd.Intn(3); i > 0; i-- {
+			obj[fmt.Sprintf("k%d", rnd.Intn(10))] = randomValue(rnd, depth+1)
+		}
+		return obj
+	}
+}
+
+func randomParameters(rnd *rand.Rand) []Parameter {
+	var params []Parameter
+	for i := rnd.Intn(3); i > 0; i-- {
+		p := Parameter{
+			Name:     fmt.Sprintf("param%d", len(params)),
+			Type:     []string{TypeString, TypeInteger, TypeNumber, TypeEnum}[rnd.Intn(4)],
+			Required: rnd.Intn(2) == 0,
+			In:       []string{"", ParamInPath, ParamInQuery, ParamInBody}[rnd.Intn(4)],
+		}
+		if rnd.Intn(3) == 0 {
+			p.Enum = []interface{}{randomValue(rnd, 2), randomValue(rnd, 2)}
+		}
+		if rnd.Intn(3) == 0 {
+			p.Default = randomValue(rnd, 2)
+		}
+		params = append(params, p)
+	}
+	return params
+}
+
+func randomSpec(rnd *rand.Rand) *OpenInfraSpec {
+	spec := &OpenInfraSpec{
+		Version:   "1.0.0",
+		Providers: make(map[string]Provider),
+		Resources: make(map[string]Resource),
+	}
+	spec.Info.Title = randomString(rnd)
+	spec.Info.Contact.Email = randomString(rnd)
+
+	for i := rnd.Intn(4); i > 0; i-- {
+		p := Provider{
+			Name: fmt.Sprintf("provider%d", rnd.Intn(100)),
+			Type: randomString(rnd),
+			Connection: Connection{
+				Protocol: "https",
+				Host:     randomString(rnd),
+				Port:     rnd.Intn(65536),
+				Authentication: Authentication{
+					Method: AuthBearer,
+					Token:  randomString(rnd),
+					Scopes: []string{randomString(rnd)},
+				},
+			},
+		}
+		for j := rnd.Intn(3); j > 0; j-- {
+			c := Capability{
+				Name:       fmt.Sprintf("cap%d", j),
+				Method:     "POST",
+				Endpoint:   "/" + randomString(rnd),
+				Parameters: randomParameters(rnd),
+				Timeout:    time.Duration(rnd.Intn(120)) * time.Second,
+			}
+			if rnd.Intn(3) == 0 {
+				c.Response = &ResponseSchema{Type: "object", Fields: randomParameters(rnd)}
+			}
+			p.Capabilities = append(p.Capabilities, c)
+		}
+		spec.Providers[p.Name] = p
+	}
+
+	var names []string
+	for i := rnd.Intn(5); i > 0; i-- {
+		r := Resource{
+			Name:       fmt.Sprintf("component%d", rnd.Intn(100)),
+			Type:       randomString(rnd),
+			Provider:   randomString(rnd),
+			Properties: map[string]interface{}{},
+		}
+		for j := rnd.Intn(5); j > 0; j-- {
+			r.Properties[randomString(rnd)] = randomValue(rnd, 0)
+		}
+		if rnd.Intn(2) == 0 {
+			r.Actions = []Action{{Name: "start", Method: "POST", Capability: randomString(rnd)}}
+		}
+		if len(names) > 0 && rnd.Intn(2) == 0 {
+			r.Dependencies = []Dependency{{DependsOn: []string{names[rnd.Intn(len(names))]}}}
+		}
+		names = append(names, r.Name)
+		spec.Resources[r.Name] = r
+	}
+	if len(names) > 1 {
+		spec.Dependencies = []Dependency{{Resource: names[0], DependsOn: names[1:]}}
+	}
+	return spec
+}
@@ -0,0 +1,530 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Разделы, которые подключаемые через includes файлы добавляют
+// к основному документу.
+var includeSections = []string{"providers", "components", "dependencies"}
+
+// refKey — ключ ссылки в стиле JSON Reference: {$ref: file.yaml#/pointer}.
+const refKey = "$ref"
+
+// loader разрешает includes и $ref, читая файлы из fsys или, если fsys
+// не задана, с диска. Запоминает, из какого файла получен каждый узел,
+// чтобы диагностика указывала на исходный файл.
+type loader struct {
+	fsys fs.FS
+	// files — файл для узлов, полученных не из основного документа
+	files map[*yaml.Node]string
+	// docs — уже прочитанные файлы
+	docs map[string]*yaml.Node
+	// including и included — стек подключаемых файлов для поиска циклов
+	// и множество уже подключённых, чтобы не подключать файл дважды
+	including []string
+	included  map[string]bool
+	// refs — стек разрешаемых ссылок для поиска циклов
+	refs []string
+	// main — ключ основного документа
+	main string
+	// local — можно читать файлы с диска (когда fsys не задана)
+	local bool
+	// base — каталог, за пределы которого не выходят пути на диске;
+	// пусто — без ограничений
+	base string
+}
+
+func newLoader(fsys fs.FS) *loader {
+	return &loader{
+		fsys:     fsys,
+		files:    make(map[*yaml.Node]string),
+		docs:     make(map[string]*yaml.Node),
+		included: make(map[string]bool),
+	}
+}
+
+// resolveIncludes подключает файлы из includes и разрешает ссылки $ref
+// основного документа root. Пути разрешаются относительно o.source,
+// а без него — относительно текущего каталога. Файлы с диска читаются
+// только с WithIncludeFiles.
+func (o *options) resolveIncludes(root *yaml.Node) error {
+	l := newLoader(o.fsys)
+	l.local = o.includeFiles
+	if o.includeFiles && !o.includeAnywhere {
+		root := o.includeRoot
+		if root == "" {
+			root = filepath.Dir(o.source)
+		}
+		base, err := realPath(root)
+		if err != nil {
+			return fmt.Errorf("ошибка при определении каталога спецификации: %w", err)
+		}
+		l.base = base
+	}
+	l.main = l.key(o.source)
+	l.docs[l.main] = root
+	if err := l.resolve(root, o.source); err != nil {
+		return err
+	}
+	o.files = l.files
+	return nil
+}
+
+// resolve подключает к документу root файлы из includes и заменяет
+// ссылки $ref на их содержимое. file — имя файла документа, относительно
+// него разрешаются пути.
+func (l *loader) resolve(root *yaml.Node, file string) error {
+	key := l.key(file)
+	l.including = append(l.including, key)
+	l.included[key] = true
+	defer func() { l.including = l.including[:len(l.including)-1] }()
+
+	body := resolveAlias(root)
+	if body == nil {
+		return nil
+	}
+	if err := l.resolveRefs(root, file); err != nil {
+		return err
+	}
+	return l.include(body, file)
+}
+
+// include подключает файлы из списка includes документа body.
+func (l *loader) include(body *yaml.Node, file string) error {
+	list := resolveAlias(mappingValue(body, "includes"))
+	if list == nil {
+		return nil
+	}
+	if list.Kind != yaml.SequenceNode {
+		return l.errorf(file, list, "includes должен быть списком путей")
+	}
+
+	for _, item := range list.Content {
+		name := scalarValue(item)
+		if name == "" {
+			return l.errorf(file, item, "includes: ожидается путь к файлу или каталогу")
+		}
+		target, err := l.join(file, name)
+		if err != nil {
+			return l.errorf(file, item, "includes: %v", err)
+		}
+
+		files, err := l.expand(target)
+		if err != nil {
+			return l.errorf(file, item, "includes: %v", err)
+		}
+		for _, f := range files {
+			if err := l.includeFile(body, f, file, item); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// includeFile читает подключаемый файл f и добавляет его разделы
+// к документу body. from и at указывают, откуда файл подключён.
+func (l *loader) includeFile(body *yaml.Node, f, from string, at *yaml.Node) error {
+	key := l.key(f)
+	for i, k := range l.including {
+		if k == key {
+			cycle := append(append([]string(nil), l.including[i:]...), key)
+			return l.errorf(from, at, "обнаружен цикл включений: %s", strings.Join(l.display(cycle), " -> "))
+		}
+	}
+	if l.included[key] {
+		// Файл уже подключён другим путём
+		return nil
+	}
+
+	root, err := l.load(f)
+	if err != nil {
+		return l.errorf(from, at, "includes: %v", err)
+	}
+	if err := l.resolve(root, f); err != nil {
+		return err
+	}
+
+	fragment := resolveAlias(root)
+	if fragment == nil {
+		return nil
+	}
+	if fragment.Kind != yaml.MappingNode {
+		return l.errorf(f, fragment, "подключаемый файл должен быть отображением")
+	}
+	for _, section := range includeSections {
+		src := resolveAlias(mappingValue(fragment, section))
+		if src == nil {
+			continue
+		}
+		if src.Kind != yaml.SequenceNode {
+			return l.errorf(f, src, "раздел %s должен быть списком", section)
+		}
+		dst, err := ensureKey(body, section, yaml.SequenceNode)
+		if err != nil {
+			return l.errorf(from, dst, "раздел %v", err)
+		}
+		dst.Content = append(dst.Content, src.Content...)
+	}
+	return nil
+}
+
+// resolveRefs заменяет в поддереве n узлы {$ref: ...} на узлы, на которые
+// они ссылаются. file — файл, в котором находится n.
+func (l *loader) resolveRefs(n *yaml.Node, file string) error {
+	if n == nil {
+		return nil
+	}
+	for i, child := range n.Content {
+		ref := refValue(child)
+		if ref == nil {
+			if err := l.resolveRefs(child, file); err != nil {
+				return err
+			}
+			continue
+		}
+		target, err := l.follow(child, ref, file)
+		if err != nil {
+			return err
+		}
+		n.Content[i] = target
+	}
+	return nil
+}
+
+// follow загружает узел, на который указывает ссылка ref узла n,
+// и разрешает ссылки внутри него.
+func (l *loader) follow(n, ref *yaml.Node, file string) (*yaml.Node, error) {
+	if len(n.Content) > 2 {
+		return nil, l.errorf(file, n, "рядом с $ref не допускаются другие ключи")
+	}
+	value := scalarValue(ref)
+	if value == "" {
+		return nil, l.errorf(file, ref, "$ref: ожидается ссылка вида file.yaml#/pointer")
+	}
+
+	target, pointer, _ := strings.Cut(value, "#")
+	targetFile := file
+	if target != "" {
+		var err error
+		if targetFile, err = l.join(file, target); err != nil {
+			return nil, l.errorf(file, ref, "$ref: %v", err)
+		}
+	}
+
+	key := l.key(targetFile) + "#" + pointer
+	for i, k := range l.refs {
+		if k == key {
+			cycle := append(append([]string(nil), l.refs[i:]...), key)
+			return nil, l.errorf(file, ref, "обнаружен цикл ссылок $ref: %s", strings.Join(cycle, " -> "))
+		}
+	}
+	l.refs = append(l.refs, key)
+	defer func() { l.refs = l.refs[:len(l.refs)-1] }()
+
+	var root *yaml.Node
+	if target == "" {
+		root = l.rootOf(n)
+	} else {
+		var err error
+		if root, err = l.load(targetFile); err != nil {
+			return nil, l.errorf(file, ref, "$ref: %v", err)
+		}
+	}
+
+	node, err := jsonPointer(root, pointer)
+	if err != nil {
+		return nil, l.errorf(file, ref, "$ref %s: %v", value, err)
+	}
+	// Узел-обёртка нужен, чтобы заменить ссылку и в корне поддерева
+	wrapper := &yaml.Node{Kind: yaml.SequenceNode, Content: []*yaml.Node{node}}
+	if err := l.resolveRefs(wrapper, targetFile); err != nil {
+		return nil, err
+	}
+	// Каждое место ссылки получает свою копию: иначе изменение одного
+	// (миграция, подстановка, правка документа) затронуло бы все
+	return l.clone(wrapper.Content[0]), nil
+}
+
+// clone копирует поддерево n и переносит на копию сведения о файлах узлов.
+func (l *loader) clone(n *yaml.Node) *yaml.Node {
+	seen := make(map[*yaml.Node]*yaml.Node)
+	c := copyNode(n, seen)
+	for orig, cn := range seen {
+		if file, exists := l.files[orig]; exists {
+			l.files[cn] = file
+		}
+	}
+	return c
+}
+
+// rootOf возвращает корень документа, которому принадлежит n, для ссылок
+// внутри того же файла (#/pointer).
+func (l *loader) rootOf(n *yaml.Node) *yaml.Node {
+	if file, exists := l.files[n]; exists {
+		return l.docs[l.key(file)]
+	}
+	return l.docs[l.main]
+}
+
+// refValue возвращает значение $ref, если n — ссылка.
+func refValue(n *yaml.Node) *yaml.Node {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	return mappingValue(n, refKey)
+}
+
+// jsonPointer спускается от корня документа по указателю RFC 6901
+// (/providers/0/capabilities/1). Пустой указатель означает весь документ.
+func jsonPointer(root *yaml.Node, pointer string) (*yaml.Node, error) {
+	n := resolveAlias(root)
+	if pointer == "" {
+		return n, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("указатель должен начинаться с /")
+	}
+	for _, token := range strings.Split(pointer[1:], "/") {
+		token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+		switch {
+		case n != nil && n.Kind == yaml.MappingNode:
+			n = resolveAlias(mappingValue(n, token))
+		case n != nil && n.Kind == yaml.SequenceNode:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(n.Content) {
+				return nil, fmt.Errorf("нет элемента %s", token)
+			}
+			n = resolveAlias(n.Content[i])
+		default:
+			n = nil
+		}
+		if n == nil {
+			return nil, fmt.Errorf("нет элемента %s", token)
+		}
+	}
+	return n, nil
+}
+
+// load читает и разбирает файл name, запоминая файл каждого узла.
+func (l *loader) load(name string) (*yaml.Node, error) {
+	key := l.key(name)
+	if root, exists := l.docs[key]; exists {
+		return root, nil
+	}
+
+	data, err := l.readFile(name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("файл %s не найден", name)
+		}
+		return nil, fmt.Errorf("ошибка при чтении файла %s: %w", name, err)
+	}
+
+	docs, err := decodeDocuments(data)
+	var jsonErr *jsonSyntaxError
+	switch {
+	case errors.As(err, &jsonErr):
+		return nil, fmt.Errorf("некорректный JSON в файле %s: %w", name, err)
+	case err != nil:
+		return nil, fmt.Errorf("некорректное форматирование YAML в файле %s: %w", name, err)
+	case len(docs) > 1:
+		return nil, fmt.Errorf("в файле %s несколько документов, ожидается один", name)
+	}
+	root := &yaml.Node{}
+	if len(docs) == 1 {
+		root = docs[0]
+	}
+	l.mark(root, name)
+	l.docs[key] = root
+	return root, nil
+}
+
+// mark запоминает файл для всех узлов поддерева n.
+func (l *loader) mark(n *yaml.Node, file string) {
+	if n == nil {
+		return
+	}
+	if _, exists := l.files[n]; exists {
+		return
+	}
+	l.files[n] = file
+	for _, c := range n.Content {
+		l.mark(c, file)
+	}
+}
+
+// expand возвращает файлы для пути из includes: сам файл или все файлы
+// *.yaml и *.yml каталога в алфавитном порядке.
+func (l *loader) expand(name string) ([]string, error) {
+	info, err := l.stat(name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("файл %s не найден", name)
+		}
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{name}, nil
+	}
+
+	entries, err := l.readDir(name)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при чтении каталога %s: %w", name, err)
+	}
+	var files []string
+	for _, e := range entries {
+		ext := path.Ext(e.Name())
+		if !e.IsDir() && (ext == ".yaml" || ext == ".yml") {
+			files = append(files, l.joinDir(name, e.Name()))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// join разрешает путь target относительно каталога файла from.
+func (l *loader) join(from, target string) (string, error) {
+	if l.fsys != nil {
+		if path.IsAbs(target) {
+			return "", fmt.Errorf("абсолютный путь %s недоступен в файловой системе", target)
+		}
+		name := path.Join(path.Dir(from), target)
+		if !fs.ValidPath(name) {
+			return "", fmt.Errorf("путь %s выходит за пределы файловой системы", target)
+		}
+		return name, nil
+	}
+	if !l.local {
+		return "", errors.New("чтение файлов с диска не разрешено " +
+			"(используйте ParseFile, ParseFS или WithIncludeFiles)")
+	}
+	if filepath.IsAbs(target) {
+		if l.base != "" {
+			return "", fmt.Errorf("абсолютный путь %s запрещён", target)
+		}
+		return filepath.Clean(target), nil
+	}
+	name := filepath.Join(filepath.Dir(from), target)
+	if l.base != "" && !l.within(name) {
+		return "", fmt.Errorf("путь %s выходит за пределы каталога спецификации", target)
+	}
+	return name, nil
+}
+
+// within сообщает, находится ли файл name внутри каталога l.base.
+// Символические ссылки раскрываются: ссылка внутри каталога на файл
+// вне его считается выходом за пределы.
+func (l *loader) within(name string) bool {
+	resolved, err := realPath(name)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(l.base, resolved)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// realPath возвращает абсолютный путь name с раскрытыми символическими
+// ссылками. Для несуществующего файла раскрывается ближайший существующий
+// каталог пути.
+func realPath(name string) (string, error) {
+	abs, err := filepath.Abs(name)
+	if err != nil {
+		return "", err
+	}
+	resolved, err := filepath.EvalSymlinks(abs)
+	if errors.Is(err, fs.ErrNotExist) {
+		dir, base := filepath.Split(abs)
+		if dir = filepath.Clean(dir); dir == abs {
+			return abs, nil
+		}
+		if dir, err = realPath(dir); err != nil {
+			return "", err
+		}
+		return filepath.Join(dir, base), nil
+	}
+	return resolved, err
+}
+
+func (l *loader) joinDir(dir, name string) string {
+	if l.fsys != nil {
+		return path.Join(dir, name)
+	}
+	return filepath.Join(dir, name)
+}
+
+// key возвращает ключ файла для обнаружения повторов и циклов.
+func (l *loader) key(name string) string {
+	if name == "" || l.fsys != nil {
+		return name
+	}
+	if abs, err := filepath.Abs(name); err == nil {
+		return abs
+	}
+	return filepath.Clean(name)
+}
+
+// display возвращает имена файлов для сообщения о цикле.
+func (l *loader) display(keys []string) []string {
+	out := make([]string, len(keys))
+	for i, k := range keys {
+		out[i] = k
+		if l.fsys == nil {
+			if wd, err := os.Getwd(); err == nil {
+				if rel, err := filepath.Rel(wd, k); err == nil && !strings.HasPrefix(rel, "..") {
+					out[i] = rel
+				}
+			}
+		}
+	}
+	return out
+}
+
+func (l *loader) readFile(name string) ([]byte, error) {
+	if l.fsys != nil {
+		return fs.ReadFile(l.fsys, name)
+	}
+	// Файлы каталога из includes проверяются здесь: среди них могут быть
+	// символические ссылки за пределы каталога спецификации
+	if l.base != "" && !l.within(name) {
+		return nil, fmt.Errorf("путь %s выходит за пределы каталога спецификации", name)
+	}
+	return os.ReadFile(name)
+}
+
+func (l *loader) stat(name string) (fs.FileInfo, error) {
+	if l.fsys != nil {
+		return fs.Stat(l.fsys, name)
+	}
+	return os.Stat(name)
+}
+
+func (l *loader) readDir(name string) ([]fs.DirEntry, error) {
+	if l.fsys != nil {
+		return fs.ReadDir(l.fsys, name)
+	}
+	return os.ReadDir(name)
+}
+
+// errorf формирует ошибку с позицией узла n в файле file.
+func (l *loader) errorf(file string, n *yaml.Node, format string, args ...interface{}) error {
+	if f, exists := l.files[n]; exists {
+		file = f
+	}
+	msg := fmt.Sprintf(format, args...)
+	if file == "" {
+		return fmt.Errorf("ошибка: %s: %s", nodePosition(file, n), msg)
+	}
+	return fmt.Errorf("ошибка в файле %s: %s", nodePosition(file, n), msg)
+}
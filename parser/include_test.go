@@ -0,0 +1,308 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// writeTree создаёт файлы files во временном каталоге и возвращает его.
+func writeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+	return dir
+}
+
+var includeTree = map[string]string{
+	"main.yaml": `openinfra: 1.1.0
+includes:
+  - providers
+  - components/web.yaml
+providers:
+  - name: local
+    type: hypervisor
+    capabilities:
+      - $ref: caps.yaml#/start
+`,
+	"caps.yaml": `start:
+  name: start
+  method: POST
+  endpoint: /start
+stop:
+  name: stop
+  method: POST
+  endpoint: /stop
+`,
+	"providers/a.yaml": `providers:
+  - name: aws
+    type: cloud
+    capabilities:
+      - $ref: ../caps.yaml#/stop
+`,
+	"providers/b.yml": `providers:
+  - name: gcp
+    type: cloud
+`,
+	"providers/README.txt": "не YAML",
+	"components/web.yaml": `includes:
+  - ../providers/a.yaml
+components:
+  - name: web
+    type: virtual_machine
+    provider: aws
+    flavour: small
+dependencies:
+  - component: web
+    depends_on: [db]
+`,
+}
+
+func TestParseFileIncludes(t *testing.T) {
+	dir := writeTree(t, includeTree)
+
+	spec, err := ParseFile(filepath.Join(dir, "main.yaml"))
+	require.NoError(t, err)
+
+	// Позиции элементов указывают на файл, в котором они объявлены
+	web := spec.Resources["web"]
+	assert.Equal(t, Position{File: filepath.Join(dir, "components", "web.yaml"), Line: 4, Column: 5}, web.Pos)
+	assert.Equal(t, Position{File: filepath.Join(dir, "caps.yaml"), Line: 6, Column: 3},
+		spec.Providers["aws"].Capabilities[0].Pos)
+	spec = stripSource(spec)
+
+	var names []string
+	for _, p := range spec.GetProviderList() {
+		names = append(names, p.Name)
+	}
+	assert.Equal(t, []string{"local", "aws", "gcp"}, names)
+
+	assert.Equal(t, []Capability{{Name: "start", Method: "POST", Endpoint: "/start"}},
+		spec.Providers["local"].Capabilities)
+	assert.Equal(t, []Capability{{Name: "stop", Method: "POST", Endpoint: "/stop"}},
+		spec.Providers["aws"].Capabilities)
+	assert.Equal(t, "aws", spec.Resources["web"].Provider)
+	assert.Equal(t, []Dependency{{Resource: "web", DependsOn: []string{"db"}}}, spec.Dependencies)
+}
+
+func TestIncludedFilePositions(t *testing.T) {
+	dir := writeTree(t, includeTree)
+	spec, err := ParseFile(filepath.Join(dir, "main.yaml"))
+	require.NoError(t, err)
+
+	// Ошибка в подключённом файле указывает на него, а не на main.yaml
+	errs := Validate(spec)
+	require.Len(t, errs, 1)
+	assert.Equal(t, CodeUnknownComponent, errs[0].Code)
+	assert.Equal(t, filepath.Join(dir, "components/web.yaml"), errs[0].File)
+	assert.Equal(t, 10, errs[0].Line)
+	assert.Equal(t, "dependencies[0].depends_on[0]", errs[0].Path)
+
+	schemaErrs, err := ValidateSchema([]byte(includeTree["main.yaml"]), WithSource(filepath.Join(dir, "main.yaml")),
+		WithIncludeFiles())
+	require.NoError(t, err)
+	require.Len(t, schemaErrs, 1)
+	assert.Equal(t, filepath.Join(dir, "components/web.yaml"), schemaErrs[0].File)
+	assert.Equal(t, 7, schemaErrs[0].Line)
+	assert.Equal(t, "components[0].flavour", schemaErrs[0].Path)
+}
+
+func TestIncludeErrors(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"missing.yaml":  "openinfra: 1.1.0\nincludes:\n  - nowhere.yaml\n",
+		"a.yaml":        "openinfra: 1.1.0\nincludes: [b.yaml]\n",
+		"b.yaml":        "includes: [a.yaml]\n",
+		"list.yaml":     "includes: [scalar.yaml]\n",
+		"scalar.yaml":   "providers: vbox\n",
+		"refcycle.yaml": "providers:\n  - $ref: '#/defs/a'\ndefs:\n  a: {$ref: '#/defs/b'}\n  b: {$ref: '#/defs/a'}\n",
+		"sibling.yaml":  "providers:\n  - $ref: caps.yaml\n    name: p\n",
+		"badref.yaml":   "providers:\n  - $ref: '#/defs/nothing'\n",
+		"mapped.yaml":   "providers:\n  vbox: {type: hypervisor}\nincludes: [one.yaml]\n",
+		"one.yaml":      "providers:\n  - name: p\n",
+	})
+	path := func(name string) string { return filepath.Join(dir, name) }
+
+	_, err := ParseFile(path("missing.yaml"))
+	assert.EqualError(t, err, "ошибка в файле "+path("missing.yaml")+":3:5: includes: файл "+
+		path("nowhere.yaml")+" не найден")
+
+	_, err = ParseFile(path("a.yaml"))
+	assert.EqualError(t, err, "ошибка в файле "+path("b.yaml")+":1:12: обнаружен цикл включений: "+
+		path("a.yaml")+" -> "+path("b.yaml")+" -> "+path("a.yaml"))
+
+	_, err = ParseFile(path("list.yaml"))
+	assert.EqualError(t, err, "ошибка в файле "+path("scalar.yaml")+":1:12: раздел providers должен быть списком")
+
+	// Раздел основного документа не того вида не дополняется
+	_, err = ParseFile(path("mapped.yaml"))
+	assert.EqualError(t, err, "ошибка в файле "+path("mapped.yaml")+":2:3: раздел providers должен быть списком")
+
+	_, err = ParseFile(path("refcycle.yaml"))
+	assert.ErrorContains(t, err, "ошибка в файле "+path("refcycle.yaml")+":5:13: обнаружен цикл ссылок $ref")
+
+	_, err = ParseFile(path("sibling.yaml"))
+	assert.EqualError(t, err, "ошибка в файле "+path("sibling.yaml")+":2:5: рядом с $ref не допускаются другие ключи")
+
+	_, err = ParseFile(path("badref.yaml"))
+	assert.EqualError(t, err, "ошибка в файле "+path("badref.yaml")+":2:11: $ref #/defs/nothing: нет элемента defs")
+}
+
+func TestParseFSIncludes(t *testing.T) {
+	fsys := fstest.MapFS{}
+	for name, content := range includeTree {
+		fsys["specs/"+name] = &fstest.MapFile{Data: []byte(content)}
+	}
+	fsys["specs/escape.yaml"] = &fstest.MapFile{Data: []byte("includes: [../../etc/passwd]\n")}
+
+	spec, err := ParseFS(fsys, "specs/main.yaml")
+	require.NoError(t, err)
+	assert.Len(t, spec.Providers, 3)
+
+	errs := Validate(spec)
+	require.Len(t, errs, 1)
+	assert.Equal(t, "specs/components/web.yaml", errs[0].File)
+
+	_, err = ParseFS(fsys, "specs/escape.yaml")
+	assert.EqualError(t, err, "ошибка в файле specs/escape.yaml:1:12: includes: путь ../../etc/passwd "+
+		"выходит за пределы файловой системы")
+}
+
+func TestParseBytesRefWithinDocument(t *testing.T) {
+	spec, err := ParseBytes([]byte(`openinfra: 1.1.0
+x-templates:
+  hypervisor:
+    type: hypervisor
+    connection: {protocol: https, host: 10.0.0.5}
+providers:
+  - $ref: '#/x-templates/hypervisor'
+components: []
+`))
+	require.NoError(t, err)
+	// Без имени провайдер попадает в карту под пустым ключом
+	assert.Equal(t, "10.0.0.5", spec.Providers[""].Connection.Host)
+
+	_, err = ParseBytes([]byte("providers:\n  - $ref: '#/nothing'\n"))
+	assert.EqualError(t, err, "ошибка: 2:11: $ref #/nothing: нет элемента nothing")
+}
+
+func TestIncludeFileAccess(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"secret.yaml":       "components:\n  - name: secret\n",
+		"specs/main.yaml":   "includes: [caps.yaml]\n",
+		"specs/caps.yaml":   "components:\n  - name: caps\n",
+		"specs/escape.yaml": "includes: [../secret.yaml]\n",
+	})
+	main := filepath.Join(dir, "specs", "main.yaml")
+
+	// Без файла-источника диск не читается
+	_, err := ParseBytes([]byte("includes: [" + main + "]\n"))
+	assert.EqualError(t, err, "ошибка: 1:12: includes: чтение файлов с диска не разрешено "+
+		"(используйте ParseFile, ParseFS или WithIncludeFiles)")
+	_, err = ParseReader(strings.NewReader("providers:\n  - $ref: "+main+"#/includes\n"), WithSource(main))
+	assert.ErrorContains(t, err, "$ref: чтение файлов с диска не разрешено")
+
+	spec, err := ParseBytes([]byte("includes: [caps.yaml]\n"), WithSource(main), WithIncludeFiles())
+	require.NoError(t, err)
+	assert.Contains(t, spec.Resources, "caps")
+
+	// Выход за каталог спецификации и абсолютные пути запрещены
+	escape := filepath.Join(dir, "specs", "escape.yaml")
+	_, err = ParseFile(escape)
+	assert.EqualError(t, err, "ошибка в файле "+escape+":1:12: includes: путь ../secret.yaml "+
+		"выходит за пределы каталога спецификации")
+	_, err = ParseBytes([]byte("includes: ["+filepath.Join(dir, "secret.yaml")+"]\n"), WithIncludeFiles())
+	assert.ErrorContains(t, err, "includes: абсолютный путь "+filepath.Join(dir, "secret.yaml")+" запрещён")
+
+	spec, err = ParseFile(escape, WithUnrestrictedIncludes())
+	require.NoError(t, err)
+	assert.Contains(t, spec.Resources, "secret")
+}
+
+func TestIncludeSymlinkEscape(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"secret/creds.yaml":   "components:\n  - name: secret\n",
+		"specs/file.yaml":     "includes: [link.yaml]\n",
+		"specs/dir.yaml":      "includes: [linkdir/creds.yaml]\n",
+		"specs/listed.yaml":   "includes: [parts]\n",
+		"specs/parts/ok.yaml": "components:\n  - name: ok\n",
+		"specs/ref.yaml":      "providers:\n  - $ref: link.yaml#/components/0\n",
+	})
+	path := func(name string) string { return filepath.Join(dir, filepath.FromSlash(name)) }
+	secret := path("secret/creds.yaml")
+	require.NoError(t, os.Symlink(secret, path("specs/link.yaml")))
+	require.NoError(t, os.Symlink(path("secret"), path("specs/linkdir")))
+	require.NoError(t, os.Symlink(secret, path("specs/parts/zz.yaml")))
+
+	for _, name := range []string{"file.yaml", "dir.yaml", "listed.yaml", "ref.yaml"} {
+		_, err := ParseFile(path("specs/" + name))
+		assert.ErrorContains(t, err, "выходит за пределы каталога спецификации", name)
+	}
+
+	// Корень можно расширить: ссылка тогда остаётся внутри него
+	spec, err := ParseFile(path("specs/file.yaml"), WithIncludeRoot(dir))
+	require.NoError(t, err)
+	assert.Contains(t, spec.Resources, "secret")
+}
+
+func TestIncludeRoot(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"shared/common.yaml": "components:\n  - name: common\n",
+		"envs/prod.yaml":     "includes: [../shared/common.yaml]\n",
+	})
+	prod := filepath.Join(dir, "envs", "prod.yaml")
+
+	_, err := ParseFile(prod)
+	assert.ErrorContains(t, err, "путь ../shared/common.yaml выходит за пределы каталога спецификации")
+
+	spec, err := ParseFile(prod, WithIncludeRoot(dir))
+	require.NoError(t, err)
+	assert.Contains(t, spec.Resources, "common")
+
+	_, err = ParseFile(prod, WithIncludeRoot(filepath.Join(dir, "envs")))
+	assert.Error(t, err)
+}
+
+func TestRefCopiedPerSite(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"main.yaml": `openinfra: 1.0.0
+providers:
+  - name: a
+    type: t
+    connection_details: {$ref: conn.yaml}
+  - name: b
+    type: t
+    connection_details: {$ref: conn.yaml}
+`,
+		"conn.yaml": "address: 10.0.0.1\nusername: admin\n",
+	})
+
+	o := &options{source: filepath.Join(dir, "main.yaml"), includeFiles: true}
+	root := &yaml.Node{}
+	require.NoError(t, yaml.Unmarshal([]byte(`providers:
+  - {$ref: "#/x-template"}
+  - {$ref: "#/x-template"}
+x-template: {name: p, type: t}
+`), root))
+	require.NoError(t, o.resolveIncludes(root))
+	items := sectionItems(resolveAlias(root), "providers")
+	assert.NotSame(t, items[0], items[1])
+
+	// Миграция переписывает connection_details каждого провайдера отдельно
+	spec, err := ParseFile(filepath.Join(dir, "main.yaml"))
+	require.NoError(t, err)
+	assert.Len(t, spec.Warnings(), 2)
+	for _, name := range []string{"a", "b"} {
+		assert.Equal(t, "10.0.0.1", spec.Providers[name].Connection.Host, name)
+		assert.Equal(t, "admin", spec.Providers[name].Connection.Authentication.Username, name)
+	}
+}
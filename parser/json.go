@@ -0,0 +1,200 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// jsonSyntaxError — ошибка разбора JSON с позицией в тексте.
+type jsonSyntaxError struct {
+	Line   int
+	Column int
+	Err    error
+}
+
+func (e *jsonSyntaxError) Error() string {
+	return fmt.Sprintf("строка %d, столбец %d: %v", e.Line, e.Column, e.Err)
+}
+
+func (e *jsonSyntaxError) Unwrap() error {
+	return e.Err
+}
+
+// looksLikeJSON сообщает, что документ начинается как JSON-объект или массив.
+func looksLikeJSON(data []byte) bool {
+	data = bytes.TrimLeft(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")), " \t\r\n")
+	return len(data) > 0 && (data[0] == '{' || data[0] == '[')
+}
+
+// decodeDocuments разбирает текст в деревья узлов документов. JSON
+// распознаётся автоматически и разбирается по правилам JSON (например,
+// экранирование \/ и повторяющиеся ключи, из которых действует последний);
+// YAML может содержать несколько документов, разделённых ---.
+func decodeDocuments(data []byte) ([]*yaml.Node, error) {
+	if looksLikeJSON(data) {
+		root, jsonErr := decodeJSON(data)
+		if jsonErr == nil {
+			return []*yaml.Node{root}, nil
+		}
+		// Документ мог быть YAML в потоковом стиле: {openinfra: 1.1.0}
+		docs, err := decodeYAML(data)
+		if err != nil {
+			return nil, jsonErr
+		}
+		return docs, nil
+	}
+	return decodeYAML(data)
+}
+
+func decodeYAML(data []byte) ([]*yaml.Node, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	var docs []*yaml.Node
+	for {
+		var root yaml.Node
+		err := dec.Decode(&root)
+		if err == io.EOF {
+			return docs, nil
+		}
+		if err != nil {
+			return docs, err
+		}
+		docs = append(docs, &root)
+	}
+}
+
+// decodeJSON разбирает JSON в дерево yaml.Node с позициями исходного текста.
+func decodeJSON(data []byte) (*yaml.Node, error) {
+	p := &jsonParser{data: data, dec: json.NewDecoder(bytes.NewReader(data))}
+	p.dec.UseNumber()
+
+	n, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+	end := p.skip(p.dec.InputOffset())
+	if _, err := p.dec.Token(); err != io.EOF {
+		return nil, p.errorAt(end, errors.New("лишние данные после JSON-документа"))
+	}
+	return &yaml.Node{Kind: yaml.DocumentNode, Line: 1, Column: 1, Content: []*yaml.Node{n}}, nil
+}
+
+type jsonParser struct {
+	data []byte
+	dec  *json.Decoder
+}
+
+// value читает очередное значение и возвращает его узел.
+func (p *jsonParser) value() (*yaml.Node, error) {
+	start := p.skip(p.dec.InputOffset())
+	tok, err := p.dec.Token()
+	if err != nil {
+		return nil, p.wrap(err)
+	}
+	line, column := p.position(start)
+
+	switch v := tok.(type) {
+	case json.Delim:
+		if v == '[' {
+			n := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Line: line, Column: column}
+			for p.dec.More() {
+				item, err := p.value()
+				if err != nil {
+					return nil, err
+				}
+				n.Content = append(n.Content, item)
+			}
+			return n, p.closing()
+		}
+
+		n := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Line: line, Column: column}
+		for p.dec.More() {
+			key, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			value, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			// Как и encoding/json, повторяющийся ключ перекрывает предыдущий
+			if i := keyIndex(n, key.Value); i >= 0 {
+				n.Content[i+1] = value
+				continue
+			}
+			n.Content = append(n.Content, key, value)
+		}
+		return n, p.closing()
+
+	case string:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: v, Style: yaml.DoubleQuotedStyle,
+			Line: line, Column: column}, nil
+	case json.Number:
+		tag := "!!int"
+		if strings.ContainsAny(v.String(), ".eE") {
+			tag = "!!float"
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: v.String(), Line: line, Column: column}, nil
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(v),
+			Line: line, Column: column}, nil
+	default: // nil
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null", Line: line, Column: column}, nil
+	}
+}
+
+// closing читает закрывающую скобку объекта или массива.
+func (p *jsonParser) closing() error {
+	if _, err := p.dec.Token(); err != nil {
+		return p.wrap(err)
+	}
+	return nil
+}
+
+// skip возвращает смещение начала следующего токена после offset.
+func (p *jsonParser) skip(offset int64) int64 {
+	for offset < int64(len(p.data)) && strings.IndexByte(" \t\r\n,:", p.data[offset]) >= 0 {
+		offset++
+	}
+	return offset
+}
+
+// position переводит смещение в байтах в строку и столбец (с 1).
+func (p *jsonParser) position(offset int64) (int, int) {
+	if offset > int64(len(p.data)) {
+		offset = int64(len(p.data))
+	}
+	before := p.data[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	lineStart := bytes.LastIndexByte(before, '\n') + 1
+	return line, utf8.RuneCount(before[lineStart:]) + 1
+}
+
+func (p *jsonParser) errorAt(offset int64, err error) error {
+	line, column := p.position(offset)
+	return &jsonSyntaxError{Line: line, Column: column, Err: err}
+}
+
+// wrap добавляет к ошибке encoding/json позицию в тексте.
+func (p *jsonParser) wrap(err error) error {
+	var syntax *json.SyntaxError
+	switch {
+	case errors.As(err, &syntax) && syntax.Offset < int64(len(p.data)):
+		// Offset указывает на байт после ошибочного символа
+		offset := syntax.Offset - 1
+		if offset < 0 {
+			offset = 0
+		}
+		return p.errorAt(offset, err)
+	case syntax != nil || err == io.EOF || errors.Is(err, io.ErrUnexpectedEOF):
+		return p.errorAt(int64(len(p.data)), errors.New("неожиданный конец JSON-документа"))
+	}
+	return p.errorAt(p.dec.InputOffset(), err)
+}
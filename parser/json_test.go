@@ -0,0 +1,196 @@
+package parser
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+const specJSON = `{
+  "openinfra": "1.1.0",
+  "providers": [
+    {"name": "cloud", "type": "cloud",
+     "connection": {"protocol": "https", "host": "api.example.com", "port": 8443}}
+  ],
+  "components": [
+    {"name": "vm", "type": "virtual_machine", "provider": "cloud",
+     "properties": {"cpu": 2, "ratio": 0.5, "url": "https:\/\/api.example.com\/vms",
+                    "backup": false, "zone": null, "size": "20", "size": "40"}},
+    {"name": "disk", "type": "volume", "provider": "missing"}
+  ]
+}`
+
+func TestParseJSON(t *testing.T) {
+	spec, err := ParseBytes([]byte(specJSON), WithSource("spec.json"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "1.1.0", spec.Version)
+	assert.Equal(t, 8443, spec.Providers["cloud"].Connection.Port)
+	assert.Equal(t, map[string]interface{}{
+		"cpu":    2,
+		"ratio":  0.5,
+		"url":    "https://api.example.com/vms",
+		"backup": false,
+		"zone":   nil,
+		// Повторяющийся ключ: действует последнее значение, строка остаётся строкой
+		"size": "40",
+	}, spec.Resources["vm"].Properties)
+
+	// Позиции указывают на JSON-текст
+	errs := Validate(spec)
+	require.Len(t, errs, 1)
+	assert.Equal(t, CodeUnknownProvider, errs[0].Code)
+	assert.Equal(t, Position{File: "spec.json", Line: 11, Column: 52}, errs[0].Position)
+}
+
+func TestParseJSONMatchesYAML(t *testing.T) {
+	var doc interface{}
+	require.NoError(t, yaml.Unmarshal([]byte(sampleYAML), &doc))
+	data, err := json.MarshalIndent(doc, "", "  ")
+	require.NoError(t, err)
+
+	fromYAML, err := ParseBytes([]byte(sampleYAML))
+	require.NoError(t, err)
+	fromJSON, err := ParseBytes(data)
+	require.NoError(t, err)
+
+	assert.Equal(t, stripSource(fromYAML), stripSource(fromJSON))
+}
+
+func TestParseYAMLFlowMapping(t *testing.T) {
+	// Документ в потоковом стиле YAML похож на JSON, но им не является
+	spec, err := ParseBytes([]byte("{openinfra: 1.1.0, components: [{name: vm, provider: p}]}"))
+	require.NoError(t, err)
+	assert.Equal(t, "1.1.0", spec.Version)
+	assert.Contains(t, spec.Resources, "vm")
+}
+
+func TestParseJSONErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		err  string
+	}{
+		{
+			name: "Нет двоеточия",
+			json: "{\n  \"openinfra\": \"1.1.0\",\n  \"info\" {}\n}",
+			err:  "ошибка: некорректный JSON в файле spec.json: строка 3, столбец 10: invalid character '{' after object key",
+		},
+		{
+			name: "Незакрытый объект",
+			json: "{\"openinfra\": \"1.1.0\"",
+			err:  "ошибка: некорректный JSON в файле spec.json: строка 1, столбец 22: неожиданный конец JSON-документа",
+		},
+		{
+			name: "Данные после документа",
+			json: "{\"openinfra\": \"1.1.0\"}\n{}",
+			err:  "ошибка: некорректный JSON в файле spec.json: строка 2, столбец 1: лишние данные после JSON-документа",
+		},
+		{
+			name: "Строка вместо числа",
+			json: `{"providers": [{"name": "p", "connection": {"port": "8443"}}]}`,
+			err:  "ошибка: некорректное форматирование YAML в файле spec.json: yaml: unmarshal errors:\n  line 1: cannot unmarshal !!str `8443` into int",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseBytes([]byte(tt.json), WithSource("spec.json"))
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func TestValidateSchemaJSON(t *testing.T) {
+	errs, err := ValidateSchema([]byte(`{"openinfra": "1.1.0", "providers": [{"name": "p", "type": "t",
+  "connection": {"port": "high"}}]}`), WithSource("spec.json"))
+	require.NoError(t, err)
+	require.Len(t, errs, 1)
+	assert.Equal(t, "providers[0].connection.port", errs[0].Path)
+	assert.Equal(t, Position{File: "spec.json", Line: 2, Column: 26}, errs[0].Position)
+}
+
+const streamYAML = `openinfra: 1.1.0
+info:
+  title: dev
+components:
+  - name: vm
+    provider: p
+---
+# Пустой документ пропускается
+---
+openinfra: 1.1.0
+info:
+  title: prod
+components:
+  - name: vm
+    provider: p
+  - name: db
+    provider: p
+`
+
+func TestParseAll(t *testing.T) {
+	specs, err := ParseAll([]byte(streamYAML), WithSource("envs.yaml"))
+	require.NoError(t, err)
+	require.Len(t, specs, 2)
+	assert.Equal(t, "dev", specs[0].Info.Title)
+	assert.Equal(t, "prod", specs[1].Info.Title)
+	assert.Equal(t, []string{"vm", "db"}, specs[1].resourceNames())
+
+	// Позиции считаются от начала потока
+	errs := Validate(specs[1])
+	require.NotEmpty(t, errs)
+	assert.Equal(t, 14, errs[0].Position.Line)
+
+	// Документ без разделителей — поток из одного документа
+	specs, err = ParseAll([]byte(specJSON))
+	require.NoError(t, err)
+	assert.Len(t, specs, 1)
+
+	_, err = ParseBytes([]byte(streamYAML))
+	assert.EqualError(t, err, "ошибка: некорректное форматирование YAML: найдено несколько документов (3), для потока документов используйте ParseAll")
+}
+
+func TestParseAllErrors(t *testing.T) {
+	_, err := ParseAll(nil)
+	assert.EqualError(t, err, "ошибка: пустая спецификация")
+
+	_, err = ParseAll([]byte(streamYAML+"---\nproviders: {name: p}\n"), WithSource("envs.yaml"))
+	var docErr *DocumentError
+	require.True(t, errors.As(err, &docErr))
+	assert.Equal(t, 3, docErr.Index)
+	assert.Contains(t, err.Error(), "документ 3: ошибка: некорректное форматирование YAML в файле envs.yaml")
+
+	_, err = ParseAll([]byte(streamYAML + "---\nproviders: [\n"))
+	require.True(t, errors.As(err, &docErr))
+	assert.Equal(t, 3, docErr.Index)
+
+	_, err = ParseAll([]byte("openinfra: 1.1.0\n---\ncompnents: []\n"), WithStrict())
+	require.True(t, errors.As(err, &docErr))
+	assert.Equal(t, 1, docErr.Index)
+	var unknown ValidationErrors
+	require.True(t, errors.As(err, &unknown))
+	assert.Equal(t, 3, unknown[0].Position.Line)
+
+	// Ошибка единственного документа не оборачивается
+	_, err = ParseAll([]byte("providers: {name: p}\n"))
+	assert.False(t, errors.As(err, &docErr))
+}
+
+func TestParseAllIncludes(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"envs.yaml": "includes: [a.yaml]\n---\nincludes: [b.json]\n",
+		"a.yaml":    "components:\n  - name: a\n",
+		"b.json":    `{"components": [{"name": "b"}]}`,
+	})
+
+	specs, err := ParseAllFile(dir + "/envs.yaml")
+	require.NoError(t, err)
+	require.Len(t, specs, 2)
+	assert.Equal(t, []string{"a"}, specs[0].resourceNames())
+	assert.Equal(t, []string{"b"}, specs[1].resourceNames())
+}
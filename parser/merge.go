@@ -0,0 +1,379 @@
+package parser
+
+import (
+	"fmt"
+	"reflect"
+
+	"gopkg.in/yaml.v3"
+)
+
+// patchKey — ключ маркера, управляющего слиянием узла наложения:
+//
+//	providers:
+//	  - name: legacy
+//	    $patch: delete   # удалить провайдера из базового документа
+//	  - name: cloud
+//	    connection:
+//	      $patch: replace  # заменить значение целиком, а не сливать
+//	      host: 10.0.0.9
+//
+// Элемент списка {$patch: replace} заменяет весь список остальными элементами.
+const patchKey = "$patch"
+
+// Значения маркера $patch.
+const (
+	PatchDelete  = "delete"
+	PatchReplace = "replace"
+)
+
+// mergeKeys — поле, по которому сопоставляются элементы списков объектов
+// при слиянии. Списки скаляров объединяются, остальные списки заменяются.
+var mergeKeys = map[reflect.Type]string{
+	reflect.TypeOf(Provider{}):   "name",
+	reflect.TypeOf(Resource{}):   "name",
+	reflect.TypeOf(Capability{}): "name",
+	reflect.TypeOf(Parameter{}):  "name",
+	reflect.TypeOf(Action{}):     "name",
+	reflect.TypeOf(Dependency{}): "component",
+}
+
+// Merge накладывает overlays по порядку на копию base и возвращает
+// результирующий документ; исходные документы не изменяются.
+//
+// Отображения сливаются по ключам, скаляры заменяются. Провайдеры,
+// компоненты, возможности, действия и параметры сопоставляются по name,
+// зависимости — по component: совпавшие элементы сливаются, новые
+// добавляются в конец списка. Списки скаляров (depends_on, scopes)
+// объединяются без повторов. Маркер $patch позволяет удалить ключ или
+// элемент списка и заменить значение целиком. Псевдонимы YAML
+// в результате раскрываются.
+func Merge(base *Document, overlays ...*Document) (*Document, error) {
+	m := &merger{files: make(map[*yaml.Node]string), fileOf: base.fileOf}
+	root, err := m.copy(base.root, base.fileOf)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, overlay := range overlays {
+		m.fileOf = overlay.fileOf
+		body, err := m.merge(root.Content[0], overlay.root.Content[0], reflect.TypeOf(rawSpec{}))
+		if err != nil {
+			return nil, err
+		}
+		root.Content[0] = body
+	}
+	return &Document{root: root, source: base.source, files: m.files}, nil
+}
+
+// MergeFiles читает базовый файл и наложения, подключает их includes
+// и ссылки $ref и сливает документы функцией Merge. Ошибки разбора
+// полученного документа указывают на файл, из которого взят узел.
+func MergeFiles(base string, overlays ...string) (*Document, error) {
+	doc, err := readResolvedDocument(base)
+	if err != nil {
+		return nil, err
+	}
+	docs := make([]*Document, len(overlays))
+	for i, filename := range overlays {
+		if docs[i], err = readResolvedDocument(filename); err != nil {
+			return nil, err
+		}
+	}
+	return Merge(doc, docs...)
+}
+
+// readResolvedDocument читает файл в документ и заменяет includes
+// и $ref их содержимым.
+func readResolvedDocument(filename string) (*Document, error) {
+	d, err := ReadDocumentFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	o := &options{source: filename, includeFiles: true}
+	if err := o.resolveIncludes(d.root); err != nil {
+		return nil, err
+	}
+	removeKey(d.root.Content[0], "includes")
+	d.files = o.files
+	return d, nil
+}
+
+// merger сливает дерево наложения с копией базового документа.
+type merger struct {
+	// files — файлы скопированных узлов
+	files map[*yaml.Node]string
+	// fileOf — файл узла текущего наложения
+	fileOf func(*yaml.Node) string
+	// copying — узлы, копируемые в данный момент, для поиска
+	// псевдонимов, ссылающихся на собственного предка
+	copying []*yaml.Node
+}
+
+func (m *merger) errorf(n *yaml.Node, format string, args ...interface{}) error {
+	file := m.fileOf(n)
+	msg := fmt.Sprintf(format, args...)
+	if file == "" {
+		return fmt.Errorf("ошибка: %s: %s", nodePosition(file, n), msg)
+	}
+	return fmt.Errorf("ошибка в файле %s: %s", nodePosition(file, n), msg)
+}
+
+// merge сливает узел наложения src с узлом dst типа t и возвращает
+// новое значение. dst может быть nil, если значения в базе нет.
+func (m *merger) merge(dst, src *yaml.Node, t reflect.Type) (*yaml.Node, error) {
+	src = resolveAlias(src)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	patch, err := m.patch(src)
+	if err != nil {
+		return nil, err
+	}
+	switch patch {
+	case PatchDelete:
+		return nil, m.errorf(mappingValue(src, patchKey),
+			"$patch: delete допускается только в значении ключа или элементе списка")
+	case PatchReplace:
+		dst = nil
+	}
+
+	if src.Kind == yaml.ScalarNode {
+		c, err := m.copy(src, m.fileOf)
+		if err != nil || dst == nil {
+			return c, err
+		}
+		// Комментарии базы сохраняются, если у наложения своих нет
+		if c.HeadComment == "" && c.LineComment == "" && c.FootComment == "" {
+			c.HeadComment, c.LineComment, c.FootComment = dst.HeadComment, dst.LineComment, dst.FootComment
+		}
+		return c, nil
+	}
+	if dst == nil || dst.Kind != src.Kind {
+		// Пустой узел того же вида с оформлением и позицией src
+		c := *src
+		c.Content, c.Anchor = nil, ""
+		m.files[&c] = m.fileOf(src)
+		dst = &c
+	}
+
+	switch src.Kind {
+	case yaml.MappingNode:
+		return dst, m.mergeMapping(dst, src, t)
+	case yaml.SequenceNode:
+		var elem reflect.Type
+		if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+			elem = t.Elem()
+		}
+		return dst, m.mergeSequence(dst, src, elem)
+	}
+	return m.copy(src, m.fileOf)
+}
+
+func (m *merger) mergeMapping(dst, src *yaml.Node, t reflect.Type) error {
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		key, value := src.Content[i], resolveAlias(src.Content[i+1])
+		if key.Value == patchKey {
+			continue
+		}
+
+		var ft reflect.Type
+		if t != nil && t.Kind() == reflect.Struct {
+			if f, known := yamlFieldByKey(t, key.Value); known {
+				ft = f.Type
+			}
+		} else if t != nil && t.Kind() == reflect.Map {
+			ft = t.Elem()
+		}
+
+		j := keyIndex(dst, key.Value)
+		patch, err := m.patch(value)
+		if err != nil {
+			return err
+		}
+		if patch == PatchDelete {
+			if j < 0 {
+				return m.errorf(key, "удаляемый ключ %s отсутствует в базовом документе", key.Value)
+			}
+			dst.Content = append(dst.Content[:j], dst.Content[j+2:]...)
+			continue
+		}
+
+		var current *yaml.Node
+		if j >= 0 {
+			current = dst.Content[j+1]
+		}
+		merged, err := m.merge(current, value, ft)
+		if err != nil {
+			return err
+		}
+		if j >= 0 {
+			dst.Content[j+1] = merged
+			continue
+		}
+		k, err := m.copy(key, m.fileOf)
+		if err != nil {
+			return err
+		}
+		dst.Content = append(dst.Content, k, merged)
+	}
+	return nil
+}
+
+func (m *merger) mergeSequence(dst, src *yaml.Node, elem reflect.Type) error {
+	items := make([]*yaml.Node, 0, len(src.Content))
+	for _, item := range src.Content {
+		item = resolveAlias(item)
+		patch, err := m.patch(item)
+		if err != nil {
+			return err
+		}
+		if patch == PatchReplace && len(item.Content) == 2 {
+			dst.Content = nil
+			continue
+		}
+		items = append(items, item)
+	}
+
+	for elem != nil && elem.Kind() == reflect.Ptr {
+		elem = elem.Elem()
+	}
+	key, keyed := mergeKeys[elem]
+	scalars := true
+	for _, item := range items {
+		scalars = scalars && item.Kind == yaml.ScalarNode
+	}
+
+	switch {
+	case keyed:
+		return m.mergeItems(dst, items, elem, key)
+	case scalars:
+		for _, item := range items {
+			if indexOfScalar(dst, item.Value) >= 0 {
+				continue
+			}
+			c, err := m.copy(item, m.fileOf)
+			if err != nil {
+				return err
+			}
+			dst.Content = append(dst.Content, c)
+		}
+		return nil
+	}
+
+	// Списки объектов без ключа сопоставить нельзя, они заменяются
+	dst.Content = nil
+	for _, item := range items {
+		c, err := m.merge(nil, item, elem)
+		if err != nil {
+			return err
+		}
+		dst.Content = append(dst.Content, c)
+	}
+	return nil
+}
+
+// mergeItems сливает элементы списка объектов, сопоставляя их по полю key.
+func (m *merger) mergeItems(dst *yaml.Node, items []*yaml.Node, elem reflect.Type, key string) error {
+	for _, item := range items {
+		if item.Kind != yaml.MappingNode {
+			return m.errorf(item, "ожидался объект с полем %s, получено %s", key, nodeTypeName(item))
+		}
+		name := scalarValue(mappingValue(item, key))
+		i, _ := findItem(dst, key, name)
+
+		patch, err := m.patch(item)
+		if err != nil {
+			return err
+		}
+		if patch == PatchDelete {
+			if i < 0 {
+				return m.errorf(item, "удаляемый элемент с %s %q отсутствует в базовом документе", key, name)
+			}
+			dst.Content = append(dst.Content[:i], dst.Content[i+1:]...)
+			continue
+		}
+
+		var current *yaml.Node
+		if i >= 0 {
+			current = resolveAlias(dst.Content[i])
+		}
+		merged, err := m.merge(current, item, elem)
+		if err != nil {
+			return err
+		}
+		if i >= 0 {
+			dst.Content[i] = merged
+		} else {
+			dst.Content = append(dst.Content, merged)
+		}
+	}
+	return nil
+}
+
+// patch возвращает значение маркера $patch отображения n или пустую строку.
+func (m *merger) patch(n *yaml.Node) (string, error) {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return "", nil
+	}
+	v := mappingValue(n, patchKey)
+	if v == nil {
+		return "", nil
+	}
+	switch value := scalarValue(v); value {
+	case PatchDelete, PatchReplace:
+		return value, nil
+	default:
+		return "", m.errorf(v, "неизвестное значение $patch %q, ожидается %s или %s", value, PatchDelete, PatchReplace)
+	}
+}
+
+// copy возвращает глубокую копию узла n без маркеров $patch, раскрывая
+// псевдонимы. Файлы копий запоминаются в m.files.
+func (m *merger) copy(n *yaml.Node, fileOf func(*yaml.Node) string) (*yaml.Node, error) {
+	if n.Kind == yaml.AliasNode {
+		for _, c := range m.copying {
+			if c == n.Alias {
+				return nil, m.errorf(n, "псевдоним *%s ссылается на содержащий его узел", n.Value)
+			}
+		}
+		m.copying = append(m.copying, n.Alias)
+		defer func() { m.copying = m.copying[:len(m.copying)-1] }()
+		return m.copy(n.Alias, fileOf)
+	}
+
+	c := *n
+	c.Anchor, c.Alias, c.Content = "", nil, nil
+	if file := fileOf(n); file != "" {
+		m.files[&c] = file
+	}
+	for i := 0; i < len(n.Content); i++ {
+		if n.Kind == yaml.MappingNode && i%2 == 0 && n.Content[i].Value == patchKey {
+			i++
+			continue
+		}
+		child, err := m.copy(n.Content[i], fileOf)
+		if err != nil {
+			return nil, err
+		}
+		c.Content = append(c.Content, child)
+	}
+	return &c, nil
+}
+
+// keyIndex возвращает индекс ключа key в отображении m или -1.
+func keyIndex(m *yaml.Node, key string) int {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// removeKey удаляет ключ key из отображения m.
+func removeKey(m *yaml.Node, key string) {
+	if i := keyIndex(m, key); i >= 0 {
+		m.Content = append(m.Content[:i], m.Content[i+2:]...)
+	}
+}
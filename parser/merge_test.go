@@ -0,0 +1,303 @@
+package parser
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const mergeBaseYAML = `openinfra: 1.1.0
+info:
+  title: Shop
+providers:
+  - name: cloud
+    type: openstack
+    connection:
+      protocol: https
+      host: cloud.dev.local # стенд разработки
+      authentication:
+        method: bearer
+        token: ${env:CLOUD_TOKEN}
+    capabilities:
+      - name: create_vm
+        method: POST
+        endpoint: /servers
+        parameters:
+          - name: flavor
+            type: string
+      - name: delete_vm
+        method: DELETE
+        endpoint: /servers/{id}
+  - name: legacy
+    type: ssh
+components:
+  - name: db
+    type: database
+    provider: cloud
+    properties:
+      size: 10
+      tags: [base]
+  - name: web
+    type: virtual_machine
+    provider: cloud
+    actions:
+      - name: start
+      - name: stop
+dependencies:
+  - component: web
+    depends_on: [db]
+`
+
+func parseDocument(t *testing.T, text string) *Document {
+	t.Helper()
+	doc, err := ParseDocument([]byte(text), WithSource(t.Name()+".yaml"))
+	require.NoError(t, err)
+	return doc
+}
+
+func TestMerge(t *testing.T) {
+	base := parseDocument(t, mergeBaseYAML)
+	prod := parseDocument(t, `providers:
+  - name: cloud
+    connection:
+      host: cloud.prod.local
+    capabilities:
+      - name: create_vm
+        timeout: 2m
+        parameters:
+          - name: flavor
+            required: true
+      - name: resize_vm
+        method: POST
+        endpoint: /servers/{id}/resize
+  - name: legacy
+    $patch: delete
+components:
+  - name: db
+    properties:
+      size: 100
+      tags: [prod]
+  - name: web
+    actions:
+      - name: stop
+        capability: shutdown
+      - name: restart
+  - name: cache
+    type: cache
+    provider: cloud
+dependencies:
+  - component: web
+    depends_on: [cache]
+`)
+	before := documentText(t, base)
+
+	doc, err := Merge(base, prod)
+	require.NoError(t, err)
+	assert.Equal(t, before, documentText(t, base), "базовый документ не должен меняться")
+
+	spec, err := doc.Spec()
+	require.NoError(t, err)
+	assert.Empty(t, Validate(spec))
+	spec = stripSource(spec)
+
+	assert.Equal(t, []string{"cloud"}, names(spec.GetProviderList()))
+	cloud := spec.Providers["cloud"]
+	assert.Equal(t, "cloud.prod.local", cloud.Connection.Host)
+	assert.Equal(t, "https", cloud.Connection.Protocol)
+	assert.Equal(t, "${env:CLOUD_TOKEN}", cloud.Connection.Authentication.Token)
+
+	require.Len(t, cloud.Capabilities, 3)
+	assert.Equal(t, Capability{
+		Name:       "create_vm",
+		Method:     "POST",
+		Endpoint:   "/servers",
+		Timeout:    2 * 60e9,
+		Parameters: []Parameter{{Name: "flavor", Type: "string", Required: true}},
+	}, cloud.Capabilities[0])
+	assert.Equal(t, "delete_vm", cloud.Capabilities[1].Name)
+	assert.Equal(t, "resize_vm", cloud.Capabilities[2].Name)
+
+	assert.Equal(t, map[string]interface{}{"size": 100, "tags": []interface{}{"base", "prod"}},
+		spec.Resources["db"].Properties)
+	assert.Equal(t, []Action{
+		{Name: "start"},
+		{Name: "stop", Capability: "shutdown"},
+		{Name: "restart"},
+	}, spec.Resources["web"].Actions)
+	assert.Equal(t, "cache", spec.Resources["cache"].Type)
+	assert.Equal(t, []Dependency{{Resource: "web", DependsOn: []string{"db", "cache"}}}, spec.Dependencies)
+
+	// Комментарии базового документа сохраняются
+	assert.Contains(t, documentText(t, doc), "host: cloud.prod.local # стенд разработки")
+}
+
+func names(providers []Provider) []string {
+	var list []string
+	for _, p := range providers {
+		list = append(list, p.Name)
+	}
+	return list
+}
+
+func TestMergePatchReplace(t *testing.T) {
+	base := parseDocument(t, mergeBaseYAML)
+	overlay := parseDocument(t, `providers:
+  - name: cloud
+    connection:
+      $patch: replace
+      protocol: ssh
+      host: 10.0.0.5
+    capabilities:
+      - $patch: replace
+      - name: ping
+components:
+  - name: db
+    properties:
+      tags: [{$patch: replace}, prod]
+      size:
+        $patch: delete
+`)
+
+	doc, err := Merge(base, overlay)
+	require.NoError(t, err)
+	spec, err := doc.Spec()
+	require.NoError(t, err)
+	spec = stripSource(spec)
+
+	cloud := spec.Providers["cloud"]
+	assert.Equal(t, Connection{Protocol: "ssh", Host: "10.0.0.5"}, cloud.Connection)
+	assert.Equal(t, []Capability{{Name: "ping"}}, cloud.Capabilities)
+	assert.Equal(t, map[string]interface{}{"tags": []interface{}{"prod"}}, spec.Resources["db"].Properties)
+	assert.NotContains(t, documentText(t, doc), "$patch")
+}
+
+func TestMergeOverlaysInOrder(t *testing.T) {
+	base := parseDocument(t, mergeBaseYAML)
+	first := parseDocument(t, "info:\n  title: Stage\n")
+	second := parseDocument(t, "info:\n  title: Prod\n  version: \"2\"\n")
+
+	doc, err := Merge(base, first, second)
+	require.NoError(t, err)
+	spec, err := doc.Spec()
+	require.NoError(t, err)
+	assert.Equal(t, "Prod", spec.Info.Title)
+	assert.Equal(t, "2", spec.Info.Version)
+}
+
+func TestMergeAliases(t *testing.T) {
+	base := parseDocument(t, `x-auth: &auth
+  method: password
+  username: admin
+providers:
+  - name: a
+    connection: {authentication: *auth}
+  - name: b
+    connection: {authentication: *auth}
+`)
+	overlay := parseDocument(t, `providers:
+  - name: a
+    connection: {authentication: {username: root}}
+`)
+
+	doc, err := Merge(base, overlay)
+	require.NoError(t, err)
+	spec, err := doc.Spec()
+	require.NoError(t, err)
+
+	// Изменение раскрытого псевдонима не затрагивает остальные его использования
+	assert.Equal(t, "root", spec.Providers["a"].Connection.Authentication.Username)
+	assert.Equal(t, "admin", spec.Providers["b"].Connection.Authentication.Username)
+}
+
+func TestMergeErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		overlay string
+		err     string
+	}{
+		{
+			name:    "Удаление отсутствующего элемента",
+			overlay: "providers:\n  - name: nope\n    $patch: delete\n",
+			err:     "ошибка в файле overlay.yaml:2:5: удаляемый элемент с name \"nope\" отсутствует в базовом документе",
+		},
+		{
+			name:    "Удаление отсутствующего ключа",
+			overlay: "info:\n  summary: {$patch: delete}\n",
+			err:     "ошибка в файле overlay.yaml:2:3: удаляемый ключ summary отсутствует в базовом документе",
+		},
+		{
+			name:    "Неизвестный маркер",
+			overlay: "info:\n  $patch: merge\n",
+			err:     "ошибка в файле overlay.yaml:2:11: неизвестное значение $patch \"merge\", ожидается delete или replace",
+		},
+		{
+			name:    "Удаление корня",
+			overlay: "$patch: delete\n",
+			err:     "ошибка в файле overlay.yaml:1:9: $patch: delete допускается только в значении ключа или элементе списка",
+		},
+		{
+			name:    "Элемент-скаляр в списке провайдеров",
+			overlay: "providers: [cloud]\n",
+			err:     "ошибка в файле overlay.yaml:1:13: ожидался объект с полем name, получено string",
+		},
+	}
+
+	base := parseDocument(t, mergeBaseYAML)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			overlay, err := ParseDocument([]byte(tt.overlay), WithSource("overlay.yaml"))
+			require.NoError(t, err)
+			_, err = Merge(base, overlay)
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func TestMergeFiles(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"base.yaml": mergeBaseYAML + "includes: [extra.yaml]\n",
+		"extra.yaml": `components:
+  - name: queue
+    type: queue
+    provider: cloud
+`,
+		"prod/overlay.yaml": `includes: [components.yaml]
+components:
+  - name: queue
+    provider: missing
+`,
+		"prod/components.yaml": `components:
+  - name: search
+    type: search
+    provider: cloud
+`,
+	})
+
+	doc, err := MergeFiles(filepath.Join(dir, "base.yaml"), filepath.Join(dir, "prod/overlay.yaml"))
+	require.NoError(t, err)
+	assert.NotContains(t, documentText(t, doc), "includes")
+
+	spec, err := doc.Spec()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"db", "web", "queue", "search"}, keys(spec.Resources))
+
+	// Ошибка указывает на наложение, из которого взято значение
+	errs := Validate(spec)
+	require.Len(t, errs, 1)
+	assert.Equal(t, CodeUnknownProvider, errs[0].Code)
+	assert.Equal(t, filepath.Join(dir, "prod/overlay.yaml"), errs[0].File)
+	assert.Equal(t, 4, errs[0].Line)
+
+	_, err = MergeFiles(filepath.Join(dir, "base.yaml"), filepath.Join(dir, "missing.yaml"))
+	assert.ErrorContains(t, err, "не найден")
+}
+
+func keys(m map[string]Resource) []string {
+	var list []string
+	for k := range m {
+		list = append(list, k)
+	}
+	return list
+}
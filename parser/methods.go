@@ -2,19 +2,88 @@ package parser
 
 import (
 	"fmt"
-	"io"
-	"net/http"
-	"strings"
+	"sort"
+
+	"gopkg.in/yaml.v3"
 )
 
+// Source возвращает метку источника спецификации (обычно имя файла)
+// или пустую строку, если она не была задана.
+func (ois *OpenInfraSpec) Source() string {
+	return ois.source
+}
+
+// fileOf возвращает файл, из которого получен узел n.
+func (ois *OpenInfraSpec) fileOf(n *yaml.Node) string {
+	if file, exists := ois.files[n]; exists {
+		return file
+	}
+	return ois.source
+}
+
+// Warnings возвращает предупреждения, полученные при разборе, например
+// об устаревшей форме записи. Разбор при этом завершается успешно.
+func (ois *OpenInfraSpec) Warnings() ValidationErrors {
+	return ois.warnings
+}
+
+// providerNames возвращает имена провайдеров в порядке исходного документа;
+// провайдеры, добавленные позже, идут следом в алфавитном порядке.
+func (ois *OpenInfraSpec) providerNames() []string {
+	names := make([]string, 0, len(ois.Providers))
+	for name := range ois.Providers {
+		names = append(names, name)
+	}
+	return orderNames(names, ois.providerOrder)
+}
+
+// resourceNames возвращает имена компонентов в порядке исходного документа.
+func (ois *OpenInfraSpec) resourceNames() []string {
+	names := make([]string, 0, len(ois.Resources))
+	for name := range ois.Resources {
+		names = append(names, name)
+	}
+	return orderNames(names, ois.resourceOrder)
+}
+
+// orderNames сортирует names: сначала в порядке order, затем по алфавиту.
+func orderNames(names, order []string) []string {
+	rank := make(map[string]int, len(order))
+	for i, name := range order {
+		rank[name] = i
+	}
+	sort.Slice(names, func(i, j int) bool {
+		ri, iok := rank[names[i]]
+		rj, jok := rank[names[j]]
+		switch {
+		case iok && jok:
+			return ri < rj
+		case iok != jok:
+			return iok
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// GetProviderList возвращает провайдеров в порядке исходного документа.
 func (ois *OpenInfraSpec) GetProviderList() []Provider {
 	var providerList []Provider
-	for _, provider := range ois.Providers {
-		providerList = append(providerList, provider)
+	for _, name := range ois.providerNames() {
+		providerList = append(providerList, ois.Providers[name])
 	}
 	return providerList
 }
 
+// GetResourceList возвращает компоненты в порядке исходного документа.
+func (ois *OpenInfraSpec) GetResourceList() []Resource {
+	var resourceList []Resource
+	for _, name := range ois.resourceNames() {
+		resourceList = append(resourceList, ois.Resources[name])
+	}
+	return resourceList
+}
+
 func (ois *OpenInfraSpec) GetProviderMap() map[string]Provider {
 	return ois.Providers
 }
@@ -79,51 +148,3 @@ func (ois *OpenInfraSpec) GetProvidersWithCapability(capabilityName string) []Pr
 	}
 	return providers
 }
-
-func (p *Provider) ExecuteCapability(name string, params map[string]interface{}) (string, error) {
-	for _, capability := range p.Capabilities {
-		if capability.Name == name {
-			// Формируем URL
-			url := p.Connection.Host
-			if p.Connection.Endpoint != "" {
-				url = p.Connection.Endpoint
-			}
-			url += capability.Endpoint
-
-			// Подставляем параметры в URL (если есть в пути)
-			for _, param := range capability.Parameters {
-				if param.Required {
-					value, exists := params[param.Name]
-					if !exists {
-						return "", fmt.Errorf("отсутствует обязательный параметр: %s", param.Name)
-					}
-					url = strings.Replace(url, "{"+param.Name+"}", fmt.Sprintf("%v", value), -1)
-				}
-			}
-
-			// Делаем HTTP-запрос
-			req, err := http.NewRequest(capability.Method, url, nil)
-			if err != nil {
-				return "", fmt.Errorf("ошибка при создании запроса: %w", err)
-			}
-
-			// Аутентификация
-			if p.Connection.Authentication.Method == "api_key" {
-				req.Header.Set("Authorization", "Bearer "+p.Connection.Authentication.APIKey)
-			} else if p.Connection.Authentication.Method == "password" {
-				req.SetBasicAuth(p.Connection.Authentication.Username, p.Connection.Authentication.Password)
-			}
-
-			client := &http.Client{}
-			resp, err := client.Do(req)
-			if err != nil {
-				return "", fmt.Errorf("ошибка при выполнении запроса: %w", err)
-			}
-			defer resp.Body.Close()
-
-			body, _ := io.ReadAll(resp.Body)
-			return string(body), nil
-		}
-	}
-	return "", fmt.Errorf("возможность %s не найдена у провайдера %s", name, p.Name)
-}
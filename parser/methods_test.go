@@ -81,3 +81,25 @@ func TestExecuteCapabilityMissing(t *testing.T) {
 	assert.Error(t, err)
 	assert.EqualError(t, err, "возможность missing-cap не найдена у провайдера test-provider")
 }
+
+func TestListsFollowDocumentOrder(t *testing.T) {
+	spec, err := ParseBytes([]byte(`openinfra: 1.1.0
+providers:
+  - {name: zeta, type: t}
+  - {name: alpha, type: t}
+components:
+  - {name: vm, type: vm, provider: zeta}
+  - {name: net, type: network, provider: alpha}
+`))
+	assert.NoError(t, err)
+
+	var providers, components []string
+	for _, p := range spec.GetProviderList() {
+		providers = append(providers, p.Name)
+	}
+	for _, r := range spec.GetResourceList() {
+		components = append(components, r.Name)
+	}
+	assert.Equal(t, []string{"zeta", "alpha"}, providers)
+	assert.Equal(t, []string{"vm", "net"}, components)
+}
@@ -0,0 +1,238 @@
+package parser
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// migrateLegacyShape обновляет документ версии 1.0 до 1.1: переводит
+// connection_details провайдеров в connection и authentication,
+// а действия, заданные строками (- start), — в объекты (- name: start).
+func migrateLegacyShape(root *yaml.Node) error {
+	_, err := rewriteLegacy(root, func(*yaml.Node) string { return "" })
+	return err
+}
+
+// CodeDeprecated — код предупреждений об устаревшей форме записи.
+const CodeDeprecated ErrorCode = "deprecated"
+
+// hasLegacyShape сообщает, есть ли в документе устаревшая форма записи.
+func hasLegacyShape(root *yaml.Node) bool {
+	for _, p := range sectionItems(root, "providers") {
+		if mappingValue(p, "connection_details") != nil {
+			return true
+		}
+	}
+	for _, c := range sectionItems(root, "components") {
+		for _, a := range sectionItems(c, "actions") {
+			if a.Kind == yaml.ScalarNode {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// rewriteLegacy переводит устаревшую форму записи в текущую и возвращает
+// по предупреждению на каждое исправленное место; файл для позиции
+// предупреждения или ошибки определяет fileOf.
+func rewriteLegacy(root *yaml.Node, fileOf func(*yaml.Node) string) (ValidationErrors, error) {
+	var warnings ValidationErrors
+	warn := func(n *yaml.Node, path yamlPath, format string, args ...interface{}) {
+		warnings = append(warnings, &ValidationError{
+			Code:     CodeDeprecated,
+			Message:  fmt.Sprintf(format, args...),
+			Path:     path.String(),
+			Position: nodePosition(fileOf(n), n),
+		})
+	}
+
+	for i, p := range sectionItems(root, "providers") {
+		key := mappingKey(p, "connection_details")
+		if key == nil {
+			continue
+		}
+		if err := migrateConnectionDetails(p); err != nil {
+			return nil, positionError(fileOf(key), key, err)
+		}
+		warn(key, yamlPath{"providers", i, "connection_details"},
+			"connection_details устарел, используйте connection (openinfra migrate перепишет файл)")
+	}
+
+	for i, c := range sectionItems(root, "components") {
+		for j, a := range sectionItems(c, "actions") {
+			if a.Kind == yaml.ScalarNode {
+				warn(a, yamlPath{"components", i, "actions", j},
+					"действие %q задано строкой, используйте - name: %s", a.Value, a.Value)
+			}
+		}
+		migrateActions(c)
+	}
+	return warnings, nil
+}
+
+// sectionItems возвращает элементы последовательности key узла n.
+func sectionItems(n *yaml.Node, key string) []*yaml.Node {
+	seq := resolveAlias(mappingValue(n, key))
+	if seq == nil || seq.Kind != yaml.SequenceNode {
+		return nil
+	}
+	items := make([]*yaml.Node, len(seq.Content))
+	for i, item := range seq.Content {
+		items[i] = resolveAlias(item)
+	}
+	return items
+}
+
+// mappingKey возвращает узел ключа key в узле-отображении.
+func mappingKey(n *yaml.Node, key string) *yaml.Node {
+	n = resolveAlias(n)
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i]
+		}
+	}
+	return nil
+}
+
+// Ключи connection_details, которые переносятся в authentication.
+// Порядок задаёт приоритет при выборе метода аутентификации.
+var legacyAuthKeys = []struct {
+	key    string
+	method string
+}{
+	{"api_key", AuthAPIKey},
+	{"token", AuthBearer},
+	{"username", AuthPassword},
+	{"password", AuthPassword},
+}
+
+// migrateConnectionDetails заменяет в провайдере p ключ connection_details
+// на connection. Адрес и порт переходят в host и port, URL API —
+// в endpoint и protocol, учётные данные — в authentication. Остальные
+// ключи сохраняются в connection как расширения x-<ключ>.
+func migrateConnectionDetails(p *yaml.Node) error {
+	if p == nil || p.Kind != yaml.MappingNode {
+		return nil
+	}
+	keyIndex := -1
+	for i := 0; i+1 < len(p.Content); i += 2 {
+		if p.Content[i].Value == "connection_details" {
+			keyIndex = i
+		}
+	}
+	if keyIndex < 0 {
+		return nil
+	}
+
+	name := scalarValue(mappingValue(p, "name"))
+	if mappingValue(p, "connection") != nil {
+		return fmt.Errorf("у провайдера %q указаны и connection, и connection_details", name)
+	}
+	details := resolveAlias(p.Content[keyIndex+1])
+	if details.Kind != yaml.MappingNode {
+		return fmt.Errorf("connection_details провайдера %q должен быть объектом", name)
+	}
+
+	// Новые узлы получают позицию connection_details, чтобы диагностика
+	// указывала на исходное место в файле.
+	at := func(n *yaml.Node) *yaml.Node {
+		n.Line, n.Column = details.Line, details.Column
+		return n
+	}
+	conn := at(&yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"})
+	set := func(m *yaml.Node, key string, value *yaml.Node) {
+		m.Content = append(m.Content, at(legacyScalar(key)), value)
+	}
+
+	auth := at(&yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"})
+	method := ""
+	for _, a := range legacyAuthKeys {
+		if v := mappingValue(details, a.key); v != nil {
+			if method == "" {
+				method = a.method
+			}
+			set(auth, a.key, v)
+		}
+	}
+
+	for i := 0; i+1 < len(details.Content); i += 2 {
+		key, value := details.Content[i].Value, details.Content[i+1]
+		switch key {
+		case "api_key", "token", "username", "password":
+			// уже перенесены в authentication
+		case "address", "host":
+			host, port, protocol := splitAddress(scalarValue(value))
+			if protocol != "" && mappingValue(details, "protocol") == nil {
+				set(conn, "protocol", at(legacyScalar(protocol)))
+			}
+			set(conn, "host", at(legacyScalar(host)))
+			if port != "" && mappingValue(details, "port") == nil {
+				set(conn, "port", at(&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: port}))
+			}
+		case "api_endpoint", "endpoint", "url":
+			if u, err := url.Parse(scalarValue(value)); err == nil && u.Scheme != "" &&
+				mappingValue(details, "protocol") == nil && mappingValue(conn, "protocol") == nil {
+				set(conn, "protocol", at(legacyScalar(u.Scheme)))
+			}
+			set(conn, "endpoint", value)
+		case "port", "protocol":
+			set(conn, key, value)
+		default:
+			set(conn, "x-"+key, value)
+		}
+	}
+
+	if method != "" {
+		auth.Content = append([]*yaml.Node{at(legacyScalar("method")), at(legacyScalar(method))}, auth.Content...)
+		set(conn, "authentication", auth)
+	}
+
+	// Ключ переименовывается на месте, чтобы сохранить его комментарии
+	p.Content[keyIndex].Value = "connection"
+	p.Content[keyIndex+1] = conn
+	return nil
+}
+
+// splitAddress разбирает адрес вида ssh://host:22 на хост, порт и схему.
+// Адрес без схемы возвращается как есть.
+func splitAddress(address string) (host, port, protocol string) {
+	if u, err := url.Parse(address); err == nil && u.Scheme != "" && u.Host != "" {
+		return u.Hostname(), u.Port(), u.Scheme
+	}
+	return address, "", ""
+}
+
+// migrateActions заменяет действия-строки компонента c объектами с именем.
+func migrateActions(c *yaml.Node) {
+	seq := resolveAlias(mappingValue(c, "actions"))
+	if seq == nil || seq.Kind != yaml.SequenceNode {
+		return
+	}
+	for i, item := range seq.Content {
+		if item.Kind != yaml.ScalarNode || strings.TrimSpace(item.Value) == "" {
+			continue
+		}
+		key := legacyScalar("name")
+		key.Line, key.Column = item.Line, item.Column
+		seq.Content[i] = &yaml.Node{
+			Kind:        yaml.MappingNode,
+			Tag:         "!!map",
+			Line:        item.Line,
+			Column:      item.Column,
+			HeadComment: item.HeadComment,
+			Content:     []*yaml.Node{key, item},
+		}
+		item.HeadComment = ""
+	}
+}
+
+func legacyScalar(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
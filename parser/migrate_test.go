@@ -0,0 +1,157 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// legacyYAML — пример спецификации из README в формате версии 1.0.
+const legacyYAML = `openinfra: 1.0.0
+providers:
+  - name: virtualbox
+    type: hypervisor
+    # адрес хоста виртуализации
+    connection_details:
+      address: 192.168.1.10
+      port: 18083
+      username: admin
+      password: password
+
+  - name: cloud_provider
+    type: cloud
+    connection_details:
+      api_endpoint: https://api.cloudprovider.com
+      api_key: your_api_key_here
+      region: eu-west-1
+
+components:
+  - type: virtual_machine
+    name: local_vm
+    provider: virtualbox
+    actions:
+      - start
+      - stop # мягкая остановка
+`
+
+func TestMigrateLegacyShape(t *testing.T) {
+	spec, err := ParseBytes([]byte(legacyYAML))
+	require.NoError(t, err)
+	spec = stripSource(spec)
+	assert.Equal(t, "1.0.0", spec.Version)
+
+	assert.Equal(t, Connection{
+		Host: "192.168.1.10",
+		Port: 18083,
+		Authentication: Authentication{
+			Method:   AuthPassword,
+			Username: "admin",
+			Password: "password",
+		},
+	}, spec.Providers["virtualbox"].Connection)
+
+	assert.Equal(t, Connection{
+		Protocol: "https",
+		Endpoint: "https://api.cloudprovider.com",
+		Authentication: Authentication{
+			Method: AuthAPIKey,
+			APIKey: "your_api_key_here",
+		},
+	}, spec.Providers["cloud_provider"].Connection)
+
+	assert.Equal(t, []Action{{Name: "start"}, {Name: "stop"}}, spec.Resources["local_vm"].Actions)
+}
+
+func TestMigrateLegacyShapeKeepsLayout(t *testing.T) {
+	doc, err := ParseDocument([]byte(legacyYAML))
+	require.NoError(t, err)
+
+	// Разбор не меняет дерево документа
+	_, err = doc.Spec()
+	require.NoError(t, err)
+	before, err := doc.Bytes()
+	require.NoError(t, err)
+	assert.Contains(t, string(before), "connection_details")
+
+	warnings, err := doc.Migrate()
+	require.NoError(t, err)
+	assert.Len(t, warnings, 4)
+	out, err := doc.Bytes()
+	require.NoError(t, err)
+
+	assert.Contains(t, string(out), "openinfra: 1.1.0\n")
+	assert.Contains(t, string(out), `    # адрес хоста виртуализации
+    connection:
+      host: 192.168.1.10
+      port: 18083
+      authentication:
+        method: password
+        username: admin
+        password: password
+`)
+	assert.Contains(t, string(out), "x-region: eu-west-1")
+	assert.Contains(t, string(out), "- name: stop # мягкая остановка")
+}
+
+func TestMigrateLegacyShapeErrors(t *testing.T) {
+	_, err := ParseBytes([]byte(`openinfra: 1.0.0
+providers:
+  - name: p
+    type: t
+    connection: {protocol: ssh}
+    connection_details: {address: h}
+`), WithSource("both.yaml"))
+	assert.EqualError(t, err, `ошибка в файле both.yaml:6:5: `+
+		`у провайдера "p" указаны и connection, и connection_details`)
+
+	// Ошибка указывает на connection_details и без поля openinfra
+	_, err = ParseBytes([]byte("providers:\n  - name: p\n    connection_details: [h]\n"))
+	assert.EqualError(t, err, `ошибка: 3:5: connection_details провайдера "p" должен быть объектом`)
+}
+
+func TestLegacyShapeWarnings(t *testing.T) {
+	spec, err := ParseBytes([]byte(legacyYAML), WithSource("legacy.yaml"))
+	require.NoError(t, err)
+
+	var got []string
+	for _, w := range spec.Warnings() {
+		assert.Equal(t, CodeDeprecated, w.Code)
+		got = append(got, w.Error())
+	}
+	assert.Equal(t, []string{
+		"legacy.yaml:6:5: connection_details устарел, используйте connection (openinfra migrate перепишет файл)",
+		"legacy.yaml:14:5: connection_details устарел, используйте connection (openinfra migrate перепишет файл)",
+		`legacy.yaml:24:9: действие "start" задано строкой, используйте - name: start`,
+		`legacy.yaml:25:9: действие "stop" задано строкой, используйте - name: stop`,
+	}, got)
+
+	// Устаревшая форма распознаётся и в документах текущей версии
+	spec, err = ParseBytes([]byte(`openinfra: 1.1.0
+providers:
+  - name: p
+    type: t
+    connection_details:
+      address: ssh://10.0.0.5:22
+      token: ${env:TOKEN}
+`))
+	require.NoError(t, err)
+	assert.Len(t, spec.Warnings(), 1)
+	assert.Equal(t, Connection{
+		Protocol:       "ssh",
+		Host:           "10.0.0.5",
+		Port:           22,
+		Authentication: Authentication{Method: AuthBearer, Token: "${env:TOKEN}"},
+	}, spec.Providers["p"].Connection)
+
+	spec, err = ParseBytes([]byte(sampleYAML))
+	require.NoError(t, err)
+	assert.Empty(t, spec.Warnings())
+}
+
+func TestDocumentMigrateUnsupportedVersion(t *testing.T) {
+	doc, err := ParseDocument([]byte("openinfra: 2.0.0\n"))
+	require.NoError(t, err)
+	_, err = doc.Migrate()
+	assert.ErrorContains(t, err, "версия 2.0.0 не поддерживается")
+}
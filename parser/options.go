@@ -0,0 +1,103 @@
+package parser
+
+import (
+	"io/fs"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Option настраивает разбор спецификации.
+type Option func(*options)
+
+type options struct {
+	// source — метка источника (обычно имя файла) для сообщений об ошибках
+	source string
+	// fsys — файловая система для includes и $ref; nil — диск
+	fsys fs.FS
+	// files — файлы узлов, подключённых через includes и $ref
+	files map[*yaml.Node]string
+	// includeFiles — includes и $ref могут читать файлы с диска, см. WithIncludeFiles
+	includeFiles bool
+	// includeAnywhere — пути includes и $ref не ограничены каталогом
+	// спецификации, см. WithUnrestrictedIncludes
+	includeAnywhere bool
+	// includeRoot — каталог, которым ограничены пути includes и $ref
+	// вместо каталога спецификации, см. WithIncludeRoot
+	includeRoot string
+	// variables — значения, заменяющие переменные раздела variables
+	variables map[string]interface{}
+	// strict — неизвестные ключи считаются ошибкой, см. WithStrict
+	strict bool
+	// recover — пропускать элементы, которые не удалось разобрать, см. WithRecovery
+	recover bool
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithSource задаёт метку источника, которая используется в сообщениях
+// об ошибках и диагностике. ParseFile и ParseFS подставляют имя файла сами.
+func WithSource(name string) Option {
+	return func(o *options) {
+		o.source = name
+	}
+}
+
+// WithIncludeFiles разрешает ParseBytes, ParseReader, ParseAll
+// и ValidateSchema читать с диска файлы из includes и $ref. Пути
+// разрешаются относительно каталога WithSource (без него — текущего
+// каталога) и не могут выходить за его пределы. ParseFile и ParseAllFile
+// включают это сами. Без этой опции документ без файла может ссылаться
+// только на себя (#/pointer): спецификация, полученная, например, по сети,
+// не должна читать произвольные локальные файлы.
+func WithIncludeFiles() Option {
+	return func(o *options) {
+		o.includeFiles = true
+	}
+}
+
+// WithIncludeRoot ограничивает пути includes и $ref на диске каталогом
+// dir вместо каталога спецификации. Например, с корнем репозитория
+// envs/prod.yaml может подключить ../shared/common.yaml. Символические
+// ссылки раскрываются, прежде чем путь сравнивается с dir. Чтение файлов
+// для ParseBytes и ParseReader по-прежнему включает WithIncludeFiles.
+func WithIncludeRoot(dir string) Option {
+	return func(o *options) {
+		o.includeRoot = dir
+	}
+}
+
+// WithUnrestrictedIncludes разрешает в includes и $ref абсолютные пути
+// и пути за пределами каталога спецификации. Используйте только
+// для спецификаций из доверенного источника.
+func WithUnrestrictedIncludes() Option {
+	return func(o *options) {
+		o.includeAnywhere = true
+	}
+}
+
+// WithVariables задаёт значения переменных для выражений ${var.name}.
+// Они заменяют одноимённые переменные из раздела variables документа.
+func WithVariables(vars map[string]interface{}) Option {
+	return func(o *options) {
+		if o.variables == nil {
+			o.variables = make(map[string]interface{})
+		}
+		for name, value := range vars {
+			o.variables[name] = value
+		}
+	}
+}
+
+// fileOf возвращает файл, из которого получен узел n.
+func (o *options) fileOf(n *yaml.Node) string {
+	if file, exists := o.files[n]; exists {
+		return file
+	}
+	return o.source
+}
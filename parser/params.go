@@ -0,0 +1,190 @@
+package parser
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ParameterError описывает ошибку в значении одного параметра.
+type ParameterError struct {
+	Name    string
+	Message string
+}
+
+func (e *ParameterError) Error() string {
+	return e.Message
+}
+
+// ParameterErrors — все ошибки параметров, найденные за одну проверку.
+type ParameterErrors []*ParameterError
+
+func (errs ParameterErrors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, e := range errs {
+		msgs[i] = e.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// ValidateParameters проверяет значения values по описаниям params:
+// обязательность, тип и допустимые значения. Возвращает копию values,
+// в которой значения приведены к объявленным типам (например, "2" → 2
+// для integer) и подставлены значения по умолчанию. Значения, для которых
+// нет описания, копируются без изменений. Ошибки возвращаются все сразу
+// в виде ParameterErrors.
+func ValidateParameters(params []Parameter, values map[string]interface{}) (map[string]interface{}, error) {
+	out := make(map[string]interface{}, len(values))
+	for k, v := range values {
+		out[k] = v
+	}
+
+	var errs ParameterErrors
+	for _, param := range params {
+		value, exists := values[param.Name]
+		if !exists || value == nil {
+			if param.Default == nil {
+				if param.Required {
+					errs = append(errs, &ParameterError{
+						Name:    param.Name,
+						Message: fmt.Sprintf("отсутствует обязательный параметр: %s", param.Name),
+					})
+				}
+				continue
+			}
+			value = param.Default
+		}
+
+		typed, err := param.Coerce(value)
+		if err != nil {
+			errs = append(errs, &ParameterError{Name: param.Name, Message: err.Error()})
+			continue
+		}
+		out[param.Name] = typed
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return out, nil
+}
+
+// Coerce приводит значение к типу параметра и проверяет его по Enum.
+func (param Parameter) Coerce(value interface{}) (interface{}, error) {
+	typed, ok := coerceValue(value, param.Type)
+	if !ok {
+		if !knownType(param.Type) {
+			return nil, fmt.Errorf("параметр %s: неизвестный тип %q", param.Name, param.Type)
+		}
+		return nil, fmt.Errorf("параметр %s: ожидался тип %s, получено %v (%s)",
+			param.Name, param.Type, value, valueTypeName(value))
+	}
+
+	if len(param.Enum) > 0 && !inEnum(typed, param.Enum) {
+		allowed := make([]string, len(param.Enum))
+		for i, e := range param.Enum {
+			allowed[i] = fmt.Sprintf("%v", e)
+		}
+		return nil, fmt.Errorf("параметр %s: значение %v не входит в список допустимых: %s",
+			param.Name, typed, strings.Join(allowed, ", "))
+	}
+	return typed, nil
+}
+
+func knownType(typ string) bool {
+	switch typ {
+	case "", TypeString, TypeInteger, TypeNumber, TypeBoolean, TypeArray, TypeObject, TypeEnum:
+		return true
+	}
+	return false
+}
+
+// coerceValue приводит значение к типу typ там, где это однозначно:
+// числа и логические значения из строк, целые из дробных без дробной части,
+// строки из скаляров.
+func coerceValue(value interface{}, typ string) (interface{}, bool) {
+	switch typ {
+	case "", TypeEnum:
+		return value, true
+	case TypeString:
+		switch v := value.(type) {
+		case string:
+			return v, true
+		case int, int64, uint64, float64, bool:
+			return fmt.Sprintf("%v", v), true
+		}
+		return nil, false
+	case TypeInteger:
+		if s, ok := value.(string); ok {
+			n, err := strconv.Atoi(strings.TrimSpace(s))
+			return n, err == nil
+		}
+		return toInt(value)
+	case TypeNumber:
+		if s, ok := value.(string); ok {
+			f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+			return f, err == nil
+		}
+		if n, ok := value.(int); ok {
+			return n, true
+		}
+		return toFloat(value)
+	case TypeBoolean:
+		switch v := value.(type) {
+		case bool:
+			return v, true
+		case string:
+			b, err := strconv.ParseBool(strings.TrimSpace(v))
+			return b, err == nil
+		}
+		return nil, false
+	case TypeArray:
+		if list, ok := value.([]interface{}); ok {
+			return list, true
+		}
+		rv := reflect.ValueOf(value)
+		if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+			return nil, false
+		}
+		list := make([]interface{}, rv.Len())
+		for i := range list {
+			list[i] = rv.Index(i).Interface()
+		}
+		return list, true
+	case TypeObject:
+		switch v := value.(type) {
+		case map[string]interface{}:
+			return v, true
+		case map[interface{}]interface{}:
+			obj := make(map[string]interface{}, len(v))
+			for k, e := range v {
+				obj[fmt.Sprintf("%v", k)] = e
+			}
+			return obj, true
+		}
+		return nil, false
+	}
+	return nil, false
+}
+
+// inEnum сравнивает значения по строковому представлению, чтобы 2 и 2.0
+// из разных источников считались равными.
+func inEnum(value interface{}, enum []interface{}) bool {
+	s := fmt.Sprintf("%v", value)
+	for _, e := range enum {
+		if fmt.Sprintf("%v", e) == s {
+			return true
+		}
+	}
+	return false
+}
+
+// valueTypeName возвращает название типа значения для сообщений.
+func valueTypeName(v interface{}) string {
+	switch v.(type) {
+	case int, int64, uint64:
+		return TypeInteger
+	}
+	return jsonTypeName(v)
+}
@@ -0,0 +1,92 @@
+package parser
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateParametersCoercion(t *testing.T) {
+	params := []Parameter{
+		{Name: "name", Type: TypeString, Required: true},
+		{Name: "cpu", Type: TypeInteger, Required: true},
+		{Name: "memory", Type: TypeInteger},
+		{Name: "ratio", Type: TypeNumber},
+		{Name: "enabled", Type: TypeBoolean, Default: true},
+		{Name: "tags", Type: TypeArray},
+		{Name: "labels", Type: TypeObject},
+		{Name: "os", Type: TypeEnum, Enum: []interface{}{"ubuntu", "debian"}, Default: "ubuntu"},
+		{Name: "port", Type: TypeInteger, Enum: []interface{}{80, 443}},
+	}
+
+	out, err := ValidateParameters(params, map[string]interface{}{
+		"name":   42,
+		"cpu":    "2",
+		"memory": 4096.0,
+		"ratio":  "0.5",
+		"tags":   []string{"a", "b"},
+		"labels": map[string]interface{}{"env": "dev"},
+		"port":   443.0,
+		"extra":  "kept",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"name":    "42",
+		"cpu":     2,
+		"memory":  4096,
+		"ratio":   0.5,
+		"enabled": true,
+		"tags":    []interface{}{"a", "b"},
+		"labels":  map[string]interface{}{"env": "dev"},
+		"os":      "ubuntu",
+		"port":    443,
+		"extra":   "kept",
+	}, out)
+}
+
+func TestValidateParametersErrors(t *testing.T) {
+	params := []Parameter{
+		{Name: "name", Type: TypeString, Required: true},
+		{Name: "cpu", Type: TypeInteger},
+		{Name: "enabled", Type: TypeBoolean},
+		{Name: "os", Type: TypeEnum, Enum: []interface{}{"ubuntu", "debian"}},
+		{Name: "size", Type: "bytes"},
+	}
+
+	_, err := ValidateParameters(params, map[string]interface{}{
+		"cpu":     2.5,
+		"enabled": "maybe",
+		"os":      "windows",
+		"size":    10,
+	})
+
+	var errs ParameterErrors
+	assert.True(t, errors.As(err, &errs))
+	assert.Len(t, errs, 5)
+	assert.Equal(t, []string{"name", "cpu", "enabled", "os", "size"},
+		[]string{errs[0].Name, errs[1].Name, errs[2].Name, errs[3].Name, errs[4].Name})
+	assert.Equal(t, "отсутствует обязательный параметр: name", errs[0].Error())
+	assert.Equal(t, "параметр cpu: ожидался тип integer, получено 2.5 (number)", errs[1].Error())
+	assert.Equal(t, "параметр os: значение windows не входит в список допустимых: ubuntu, debian", errs[3].Error())
+	assert.Equal(t, `параметр size: неизвестный тип "bytes"`, errs[4].Error())
+}
+
+func TestParameterEnumYAML(t *testing.T) {
+	spec, err := ParseBytes([]byte(`
+providers:
+  - name: p
+    capabilities:
+      - name: create
+        parameters:
+          - name: os
+            type: enum
+            enum: [ubuntu, debian]
+            default: debian
+`))
+	assert.NoError(t, err)
+
+	param := spec.Providers["p"].Capabilities[0].Parameters[0]
+	assert.Equal(t, []interface{}{"ubuntu", "debian"}, param.Enum)
+	assert.Equal(t, "debian", param.Default)
+}
@@ -3,18 +3,53 @@ package parser
 import (
 	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 
 	"gopkg.in/yaml.v3"
 )
 
-// ParseFile читает и парсит YAML-файл OpenInfra.
-func ParseFile(filename string) (*OpenInfraSpec, error) {
+// rawSpec описывает документ OpenInfra в том виде, в каком он записан в YAML:
+// провайдеры и компоненты задаются списками, а не картами.
+type rawSpec struct {
+	Version      string                 `yaml:"openinfra"`
+	Info         Info                   `yaml:"info"`
+	Variables    map[string]interface{} `yaml:"variables,omitempty"`
+	Providers    []Provider             `yaml:"providers"`
+	Resources    []Resource             `yaml:"components"`
+	Dependencies []Dependency           `yaml:"dependencies,omitempty"`
+	// Includes — файлы и каталоги, разделы которых добавляются к документу
+	Includes []string `yaml:"includes,omitempty"`
+}
+
+// ParseFile читает и парсит файл OpenInfra в формате YAML или JSON.
+func ParseFile(filename string, opts ...Option) (*OpenInfraSpec, error) {
+	data, err := readSpecFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return ParseBytes(data, append([]Option{WithSource(filename), WithIncludeFiles()}, opts...)...)
+}
+
+// ParseAllFile читает файл с потоком YAML-документов и парсит каждый
+// из них (см. ParseAll).
+func ParseAllFile(filename string, opts ...Option) ([]*OpenInfraSpec, error) {
+	data, err := readSpecFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return ParseAll(data, append([]Option{WithSource(filename), WithIncludeFiles()}, opts...)...)
+}
+
+// readSpecFile читает файл спецификации с понятными ошибками
+// для отсутствующего, недоступного и пустого файла.
+func readSpecFile(filename string) ([]byte, error) {
 	// Проверяем, существует ли файл
 	fileInfo, err := os.Stat(filename)
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
-			return nil, fmt.Errorf("ошибка: файл %s не найден", filename)
+			return nil, fileNotFound(filename, err)
 		}
 		return nil, fmt.Errorf("ошибка при получении информации о файле: %w", err)
 	}
@@ -23,7 +58,7 @@ func ParseFile(filename string) (*OpenInfraSpec, error) {
 	file, err := os.Open(filename)
 	if err != nil {
 		if errors.Is(err, os.ErrPermission) {
-			return nil, fmt.Errorf("ошибка: недостаточно прав для чтения файла %s", filename)
+			return nil, fileNotReadable(filename, err)
 		}
 		return nil, fmt.Errorf("ошибка при открытии файла: %w", err)
 	}
@@ -35,39 +70,315 @@ func ParseFile(filename string) (*OpenInfraSpec, error) {
 	}
 
 	// Читаем содержимое файла
-	data, err := os.ReadFile(filename)
+	data, err := io.ReadAll(file)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка при чтении файла %s: %w", filename, err)
 	}
+	return data, nil
+}
+
+// fileError — ошибка доступа к файлу спецификации: текст сообщения
+// не включает системную ошибку, но errors.Is(err, fs.ErrNotExist)
+// и errors.Is(err, fs.ErrPermission) для неё работают.
+type fileError struct {
+	msg string
+	err error
+}
+
+func (e *fileError) Error() string {
+	return e.msg
+}
+
+func (e *fileError) Unwrap() error {
+	return e.err
+}
+
+func fileNotFound(name string, err error) error {
+	return &fileError{msg: fmt.Sprintf("ошибка: файл %s не найден", name), err: err}
+}
+
+func fileNotReadable(name string, err error) error {
+	return &fileError{msg: fmt.Sprintf("ошибка: недостаточно прав для чтения файла %s", name), err: err}
+}
+
+// ParseFS читает и парсит файл OpenInfra из файловой системы fsys,
+// например из embed.FS.
+func ParseFS(fsys fs.FS, name string, opts ...Option) (*OpenInfraSpec, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			return nil, fileNotFound(name, err)
+		case errors.Is(err, fs.ErrPermission):
+			return nil, fileNotReadable(name, err)
+		}
+		return nil, fmt.Errorf("ошибка при чтении файла %s: %w", name, err)
+	}
+
+	o := newOptions(append([]Option{WithSource(name)}, opts...))
+	o.fsys = fsys
+	return parse(data, o)
+}
+
+// ParseReader читает спецификацию OpenInfra из r целиком и парсит её.
+func ParseReader(r io.Reader, opts ...Option) (*OpenInfraSpec, error) {
+	o := newOptions(opts)
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		if o.source != "" {
+			return nil, fmt.Errorf("ошибка при чтении файла %s: %w", o.source, err)
+		}
+		return nil, fmt.Errorf("ошибка при чтении спецификации: %w", err)
+	}
+
+	return parse(data, o)
+}
+
+// ParseBytes парсит спецификацию OpenInfra из среза байт. Формат (YAML
+// или JSON) определяется по содержимому.
+func ParseBytes(data []byte, opts ...Option) (*OpenInfraSpec, error) {
+	return parse(data, newOptions(opts))
+}
+
+// ParseAll парсит поток YAML-документов, разделённых ---, и возвращает
+// по спецификации на каждый документ; пустые документы пропускаются.
+// Если в потоке несколько документов, ошибка возвращается
+// как *DocumentError с номером документа. В режиме восстановления
+// (WithRecovery) возвращаются все документы, а ошибки пропущенных
+// элементов всех документов собираются в один ValidationErrors.
+func ParseAll(data []byte, opts ...Option) ([]*OpenInfraSpec, error) {
+	o := newOptions(opts)
+	if len(data) == 0 {
+		return nil, o.emptyError()
+	}
+
+	docs, err := decodeDocuments(data)
+	if err != nil {
+		// Документы до ошибки прочитаны: значит, ошибка не в первом
+		if len(docs) > 0 {
+			return nil, &DocumentError{Index: len(docs), Err: o.yamlError(err)}
+		}
+		return nil, o.yamlError(err)
+	}
+
+	var specs []*OpenInfraSpec
+	var diagnostics ValidationErrors
+	for i, root := range docs {
+		if isEmptyDocument(root) {
+			continue
+		}
+		// У каждого документа свои включённые файлы
+		do := *o
+		do.files = nil
+		spec, err := parseDocumentNode(root, &do)
+		if spec != nil && err != nil {
+			// Частичная спецификация режима восстановления
+			var errs ValidationErrors
+			errors.As(err, &errs)
+			diagnostics = append(diagnostics, errs...)
+			err = nil
+		}
+		if err != nil && len(docs) > 1 {
+			return nil, &DocumentError{Index: i, Err: err}
+		}
+		if err != nil {
+			return nil, err
+		}
+		specs = append(specs, spec)
+	}
+	return specs, diagnostics.Err()
+}
+
+// isEmptyDocument сообщает, что в документе нет ничего, кроме комментариев.
+func isEmptyDocument(root *yaml.Node) bool {
+	n := resolveAlias(root)
+	return n == nil || n.Kind == yaml.DocumentNode || (n.Kind == yaml.ScalarNode && n.ShortTag() == "!!null")
+}
+
+// DocumentError — ошибка разбора одного документа потока (см. ParseAll).
+type DocumentError struct {
+	// Index — номер документа в потоке, начиная с 0
+	Index int
+	Err   error
+}
+
+func (e *DocumentError) Error() string {
+	return fmt.Sprintf("документ %d: %v", e.Index, e.Err)
+}
+
+func (e *DocumentError) Unwrap() error {
+	return e.Err
+}
+
+// parse — общая часть всех точек входа: преобразует YAML или JSON
+// в OpenInfraSpec.
+func parse(data []byte, o *options) (*OpenInfraSpec, error) {
+	if len(data) == 0 {
+		return nil, o.emptyError()
+	}
+
+	// Парсим текст в дерево узлов: оно нужно для позиций в диагностике
+	docs, err := decodeDocuments(data)
+	if err != nil {
+		return nil, o.yamlError(err)
+	}
+	switch len(docs) {
+	case 0:
+		return parseDocumentNode(&yaml.Node{}, o)
+	case 1:
+		return parseDocumentNode(docs[0], o)
+	}
+	return nil, o.yamlError(fmt.Errorf("найдено несколько документов (%d), для потока документов используйте ParseAll", len(docs)))
+}
+
+// parseDocumentNode разрешает includes документа root и преобразует его
+// в OpenInfraSpec.
+func parseDocumentNode(root *yaml.Node, o *options) (*OpenInfraSpec, error) {
+	if err := o.resolveIncludes(root); err != nil {
+		return nil, err
+	}
+	return parseNode(root, o)
+}
 
-	var rawSpec struct {
-		Version      string       `yaml:"openinfra"`
-		Info         Info         `yaml:"info"`
-		Providers    []Provider   `yaml:"providers"`
-		Resources    []Resource   `yaml:"components"`
-		Dependencies []Dependency `yaml:"dependencies"`
+// emptyError возвращает ошибку пустой спецификации.
+func (o *options) emptyError() error {
+	if o.source != "" {
+		return fmt.Errorf("ошибка: файл %s пуст", o.source)
 	}
+	return errors.New("ошибка: пустая спецификация")
+}
 
-	// Парсим YAML
-	if err := yaml.Unmarshal(data, &rawSpec); err != nil {
-		return nil, fmt.Errorf("ошибка: некорректное форматирование YAML в файле %s: %w", filename, err)
+// parseNode приводит дерево узлов документа к текущей версии формата
+// и преобразует его в OpenInfraSpec.
+func parseNode(root *yaml.Node, o *options) (*OpenInfraSpec, error) {
+	root, warnings, err := o.migrate(root, false)
+	if err != nil {
+		return nil, err
+	}
+	if root, err = o.interpolate(root); err != nil {
+		return nil, err
 	}
 
-	// Создаём структуру с провайдерами в виде карты
+	// diagnostics — ошибки, с которыми в режиме восстановления
+	// возвращается частичная спецификация
+	var diagnostics ValidationErrors
+	if o.strict {
+		diagnostics = append(diagnostics, checkKnownFields(root, o.fileOf)...)
+	}
+	diagnostics = append(diagnostics, checkComponentProperties(root, o.fileOf)...)
+	if len(diagnostics) > 0 && !o.recover {
+		return nil, diagnostics
+	}
+
+	var raw rawSpec
+	switch {
+	case root.IsZero():
+	case o.recover:
+		diagnostics = append(diagnostics, o.decodeRecovering(root, &raw)...)
+	default:
+		if err := root.Decode(&raw); err != nil {
+			return nil, o.yamlError(err)
+		}
+		raw.setPositions(root, o.fileOf)
+	}
+
+	spec := raw.toSpec(o.source)
+	spec.root = root
+	spec.files = o.files
+	spec.warnings = warnings
+	return spec, diagnostics.Err()
+}
+
+// yamlError оборачивает ошибку разбора YAML или JSON с указанием источника.
+func (o *options) yamlError(err error) error {
+	var jsonErr *jsonSyntaxError
+	if errors.As(err, &jsonErr) {
+		if o.source != "" {
+			return fmt.Errorf("ошибка: некорректный JSON в файле %s: %w", o.source, err)
+		}
+		return fmt.Errorf("ошибка: некорректный JSON: %w", err)
+	}
+	if o.source != "" {
+		return fmt.Errorf("ошибка: некорректное форматирование YAML в файле %s: %w", o.source, err)
+	}
+	return fmt.Errorf("ошибка: некорректное форматирование YAML: %w", err)
+}
+
+// toSpec создаёт структуру с провайдерами и компонентами в виде карт.
+func (raw *rawSpec) toSpec(source string) *OpenInfraSpec {
 	spec := &OpenInfraSpec{
-		Version:      rawSpec.Version,
-		Info:         rawSpec.Info,
+		Version:      raw.Version,
+		Info:         raw.Info,
+		Variables:    raw.Variables,
 		Providers:    make(map[string]Provider),
 		Resources:    make(map[string]Resource),
-		Dependencies: rawSpec.Dependencies,
+		Dependencies: raw.Dependencies,
+		source:       source,
 	}
 
-	for _, p := range rawSpec.Providers {
+	raw.normalize()
+
+	for _, p := range raw.Providers {
+		if _, exists := spec.Providers[p.Name]; !exists {
+			spec.providerOrder = append(spec.providerOrder, p.Name)
+		}
 		spec.Providers[p.Name] = p
 	}
-	for _, r := range rawSpec.Resources {
+	for _, r := range raw.Resources {
+		if _, exists := spec.Resources[r.Name]; !exists {
+			spec.resourceOrder = append(spec.resourceOrder, r.Name)
+		}
 		spec.Resources[r.Name] = r
 	}
 
-	return spec, nil
+	return spec
+}
+
+// normalize заменяет пустые списки и карты на nil: в YAML пустой список
+// и отсутствующий ключ означают одно и то же, а GenerateYAML пустые
+// коллекции не записывает.
+func (raw *rawSpec) normalize() {
+	if len(raw.Dependencies) == 0 {
+		raw.Dependencies = nil
+	}
+	normalizeDependencies(raw.Dependencies)
+
+	for i := range raw.Providers {
+		p := &raw.Providers[i]
+		if len(p.Capabilities) == 0 {
+			p.Capabilities = nil
+		}
+		for j := range p.Capabilities {
+			c := &p.Capabilities[j]
+			if len(c.Parameters) == 0 {
+				c.Parameters = nil
+			}
+			if c.Response != nil && len(c.Response.Fields) == 0 {
+				c.Response.Fields = nil
+			}
+		}
+	}
+
+	for i := range raw.Resources {
+		r := &raw.Resources[i]
+		if len(r.Properties) == 0 {
+			r.Properties = nil
+		}
+		if len(r.Actions) == 0 {
+			r.Actions = nil
+		}
+		if len(r.Dependencies) == 0 {
+			r.Dependencies = nil
+		}
+		normalizeDependencies(r.Dependencies)
+	}
+}
+
+func normalizeDependencies(deps []Dependency) {
+	for i := range deps {
+		if len(deps[i].DependsOn) == 0 {
+			deps[i].DependsOn = nil
+		}
+	}
 }
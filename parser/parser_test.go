@@ -2,8 +2,11 @@ package parser
 
 import (
 	"errors"
+	"io/fs"
 	"os"
+	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -357,6 +360,128 @@ func TestParseFileErrors(t *testing.T) {
 	}
 }
 
+func TestParseBytes(t *testing.T) {
+	spec, err := ParseBytes([]byte(sampleYAML))
+	assert.NoError(t, err)
+	assert.NotNil(t, spec)
+
+	assert.Equal(t, "1.0.0", spec.Version)
+	assert.Len(t, spec.Providers, 2)
+	assert.Len(t, spec.Resources, 2)
+	assert.Equal(t, "", spec.Source())
+
+	spec, err = ParseBytes([]byte(sampleYAML), WithSource("inline.yaml"))
+	assert.NoError(t, err)
+	assert.Equal(t, "inline.yaml", spec.Source())
+}
+
+func TestParseBytesErrors(t *testing.T) {
+	_, err := ParseBytes(nil)
+	assert.EqualError(t, err, "ошибка: пустая спецификация")
+
+	_, err = ParseBytes(nil, WithSource("empty.yaml"))
+	assert.EqualError(t, err, "ошибка: файл empty.yaml пуст")
+
+	_, err = ParseBytes([]byte("invalid_yaml: [unterminated"), WithSource("broken.yaml"))
+	assert.Error(t, err)
+	assert.True(t, contains(err.Error(), "ошибка: некорректное форматирование YAML в файле broken.yaml"))
+}
+
+func TestParseReader(t *testing.T) {
+	spec, err := ParseReader(strings.NewReader(sampleYAML), WithSource("stdin"))
+	assert.NoError(t, err)
+	assert.Equal(t, "stdin", spec.Source())
+	assert.Equal(t, "OpenInfra Specification", spec.Info.Title)
+
+	_, err = ParseReader(strings.NewReader(""))
+	assert.EqualError(t, err, "ошибка: пустая спецификация")
+}
+
+func TestParseFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"specs/infra.yaml": {Data: []byte(sampleYAML)},
+	}
+
+	spec, err := ParseFS(fsys, "specs/infra.yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, "specs/infra.yaml", spec.Source())
+	assert.True(t, spec.HasProvider("cloud_provider"))
+
+	_, err = ParseFS(fsys, "specs/missing.yaml")
+	assert.Error(t, err)
+	assert.True(t, errors.Is(err, fs.ErrNotExist))
+	assert.True(t, contains(err.Error(), "ошибка: файл specs/missing.yaml не найден"))
+}
+
+// deniedFS — файловая система, в которой нет прав на чтение файлов.
+type deniedFS struct{}
+
+func (deniedFS) Open(name string) (fs.File, error) {
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+}
+
+func TestFileErrorMessages(t *testing.T) {
+	// Текст ошибки не зависит от системной ошибки, а errors.Is работает
+	_, err := ParseFile("nonexistent.yaml")
+	assert.EqualError(t, err, "ошибка: файл nonexistent.yaml не найден")
+	assert.True(t, errors.Is(err, fs.ErrNotExist))
+
+	_, err = ParseFS(fstest.MapFS{}, "specs/missing.yaml")
+	assert.EqualError(t, err, "ошибка: файл specs/missing.yaml не найден")
+	assert.True(t, errors.Is(err, fs.ErrNotExist))
+
+	_, err = ParseFS(deniedFS{}, "specs/secret.yaml")
+	assert.EqualError(t, err, "ошибка: недостаточно прав для чтения файла specs/secret.yaml")
+	assert.True(t, errors.Is(err, fs.ErrPermission))
+	var pathErr *fs.PathError
+	assert.True(t, errors.As(err, &pathErr))
+}
+
+func TestParsePositions(t *testing.T) {
+	spec, err := ParseBytes([]byte(`openinfra: 1.1.0
+providers:
+  - name: cloud
+    type: cloud
+    capabilities:
+      - name: start
+components:
+  - name: vm
+    provider: cloud
+    actions:
+      - name: start
+    dependencies:
+      - depends_on: [net]
+  - name: net
+    provider: cloud
+dependencies:
+  - component: vm
+    depends_on: [net]
+`), WithSource("spec.yaml"))
+	assert.NoError(t, err)
+
+	cloud := spec.Providers["cloud"]
+	assert.Equal(t, Position{File: "spec.yaml", Line: 3, Column: 5}, cloud.Pos)
+	assert.Equal(t, Position{File: "spec.yaml", Line: 6, Column: 9}, cloud.Capabilities[0].Pos)
+
+	vm := spec.Resources["vm"]
+	assert.Equal(t, Position{File: "spec.yaml", Line: 8, Column: 5}, vm.Pos)
+	assert.Equal(t, Position{File: "spec.yaml", Line: 11, Column: 9}, vm.Actions[0].Pos)
+	assert.Equal(t, Position{File: "spec.yaml", Line: 13, Column: 9}, vm.Dependencies[0].Pos)
+	assert.Equal(t, Position{File: "spec.yaml", Line: 14, Column: 5}, spec.Resources["net"].Pos)
+	assert.Equal(t, Position{File: "spec.yaml", Line: 17, Column: 5}, spec.Dependencies[0].Pos)
+
+	// Позиции не попадают в сгенерированный YAML
+	out, err := GenerateYAML(spec)
+	assert.NoError(t, err)
+	assert.NotContains(t, out, "pos")
+}
+
+func TestPositionErrorf(t *testing.T) {
+	assert.EqualError(t, Position{}.Errorf("компонент %q", "vm"), `компонент "vm"`)
+	assert.EqualError(t, Position{File: "spec.yaml", Line: 3, Column: 5}.Errorf("компонент %q", "vm"),
+		`spec.yaml:3:5: компонент "vm"`)
+}
+
 // contains проверяет, содержит ли строка подстроку (для упрощенной проверки ошибок)
 func contains(str, substr string) bool {
 	return len(str) >= len(substr) && str[:len(substr)] == substr
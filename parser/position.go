@@ -0,0 +1,211 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Position указывает место в исходном документе.
+type Position struct {
+	File   string `json:"file,omitempty"`
+	Line   int    `json:"line,omitempty"`
+	Column int    `json:"column,omitempty"`
+}
+
+// IsValid сообщает, известна ли позиция.
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+// String возвращает позицию в виде file:line:column.
+func (p Position) String() string {
+	s := p.File
+	if p.IsValid() {
+		if s != "" {
+			s += ":"
+		}
+		s += fmt.Sprintf("%d:%d", p.Line, p.Column)
+	}
+	if s == "" {
+		s = "-"
+	}
+	return s
+}
+
+// Errorf возвращает ошибку с сообщением по формату format. Если позиция
+// известна, сообщение начинается с неё: spec.yaml:12:5: ...
+func (p Position) Errorf(format string, args ...interface{}) error {
+	err := fmt.Errorf(format, args...)
+	if !p.IsValid() {
+		return err
+	}
+	return fmt.Errorf("%s: %w", p, err)
+}
+
+// nodePosition возвращает позицию узла в файле file.
+func nodePosition(file string, n *yaml.Node) Position {
+	if n == nil {
+		return Position{File: file}
+	}
+	return Position{File: file, Line: n.Line, Column: n.Column}
+}
+
+// yamlPath — путь к узлу документа: ключи отображений (string)
+// и индексы последовательностей (int).
+type yamlPath []interface{}
+
+// String форматирует путь в виде components[1].provider.
+func (p yamlPath) String() string {
+	var sb strings.Builder
+	for _, el := range p {
+		switch v := el.(type) {
+		case int:
+			sb.WriteString("[" + strconv.Itoa(v) + "]")
+		default:
+			if sb.Len() > 0 {
+				sb.WriteByte('.')
+			}
+			sb.WriteString(fmt.Sprint(v))
+		}
+	}
+	return sb.String()
+}
+
+// with возвращает новый путь, дополненный элементами els.
+func (p yamlPath) with(els ...interface{}) yamlPath {
+	out := make(yamlPath, 0, len(p)+len(els))
+	out = append(out, p...)
+	return append(out, els...)
+}
+
+// lookupNode спускается от узла n по пути path. Возвращает nil,
+// если какого-либо элемента пути нет.
+func lookupNode(n *yaml.Node, path yamlPath) *yaml.Node {
+	for _, el := range path {
+		n = resolveAlias(n)
+		if n == nil {
+			return nil
+		}
+		switch v := el.(type) {
+		case int:
+			if n.Kind != yaml.SequenceNode || v < 0 || v >= len(n.Content) {
+				return nil
+			}
+			n = n.Content[v]
+		case string:
+			n = mappingValue(n, v)
+		default:
+			return nil
+		}
+	}
+	return n
+}
+
+// mappingValue возвращает значение ключа key в узле-отображении.
+func mappingValue(n *yaml.Node, key string) *yaml.Node {
+	n = resolveAlias(n)
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// resolveAlias раскрывает документ и алиасы до узла с содержимым.
+func resolveAlias(n *yaml.Node) *yaml.Node {
+	for n != nil {
+		switch {
+		case n.Kind == yaml.DocumentNode && len(n.Content) > 0:
+			n = n.Content[0]
+		case n.Kind == yaml.AliasNode:
+			n = n.Alias
+		default:
+			return n
+		}
+	}
+	return nil
+}
+
+// scalarValue возвращает значение скалярного узла или пустую строку.
+func scalarValue(n *yaml.Node) string {
+	n = resolveAlias(n)
+	if n == nil || n.Kind != yaml.ScalarNode {
+		return ""
+	}
+	return n.Value
+}
+
+// itemIndex ищет в последовательности section элемент с полем key,
+// равным name, и возвращает индекс последнего такого элемента или -1.
+// Последний — потому что при сборке карт более поздний элемент
+// перекрывает ранние.
+func itemIndex(root *yaml.Node, section, key, name string) int {
+	seq := resolveAlias(mappingValue(root, section))
+	if seq == nil || seq.Kind != yaml.SequenceNode {
+		return -1
+	}
+	for i := len(seq.Content) - 1; i >= 0; i-- {
+		if scalarValue(mappingValue(seq.Content[i], key)) == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// setPositions заполняет Pos провайдеров, возможностей, компонентов,
+// действий и зависимостей по узлам документа root, из которого получен raw.
+func (raw *rawSpec) setPositions(root *yaml.Node, fileOf func(*yaml.Node) string) {
+	body := resolveAlias(root)
+
+	providers := sectionItems(body, "providers")
+	for i := range raw.Providers {
+		if i < len(providers) {
+			raw.Providers[i].setPositions(providers[i], fileOf)
+		}
+	}
+	components := sectionItems(body, "components")
+	for i := range raw.Resources {
+		if i < len(components) {
+			raw.Resources[i].setPositions(components[i], fileOf)
+		}
+	}
+	setDependencyPositions(raw.Dependencies, sectionItems(body, "dependencies"), fileOf)
+}
+
+// setPositions заполняет Pos провайдера и его возможностей по узлу n.
+func (p *Provider) setPositions(n *yaml.Node, fileOf func(*yaml.Node) string) {
+	p.Pos = nodePosition(fileOf(n), n)
+	capabilities := sectionItems(n, "capabilities")
+	for i := range p.Capabilities {
+		if i < len(capabilities) {
+			p.Capabilities[i].Pos = nodePosition(fileOf(capabilities[i]), capabilities[i])
+		}
+	}
+}
+
+// setPositions заполняет Pos компонента, его действий и зависимостей по узлу n.
+func (r *Resource) setPositions(n *yaml.Node, fileOf func(*yaml.Node) string) {
+	r.Pos = nodePosition(fileOf(n), n)
+	actions := sectionItems(n, "actions")
+	for i := range r.Actions {
+		if i < len(actions) {
+			r.Actions[i].Pos = nodePosition(fileOf(actions[i]), actions[i])
+		}
+	}
+	setDependencyPositions(r.Dependencies, sectionItems(n, "dependencies"), fileOf)
+}
+
+func setDependencyPositions(deps []Dependency, items []*yaml.Node, fileOf func(*yaml.Node) string) {
+	for i := range deps {
+		if i < len(items) {
+			deps[i].Pos = nodePosition(fileOf(items[i]), items[i])
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CodeInvalidValue — значение не подходит к типу поля: элемент пропущен
+// в режиме восстановления или свойство не соответствует структуре,
+// зарегистрированной для типа компонента.
+const CodeInvalidValue ErrorCode = "invalid_value"
+
+// WithRecovery включает режим восстановления: провайдеры, компоненты,
+// зависимости и остальные ключи верхнего уровня разбираются независимо,
+// а элементы, которые не удалось разобрать, пропускаются. Разбор
+// возвращает частичную спецификацию вместе с ValidationErrors, где
+// для каждого пропущенного элемента указаны путь и позиция. В строгом
+// режиме неизвестные ключи тоже попадают в этот список.
+//
+// Ошибки, после которых документ прочитать нельзя (синтаксис YAML,
+// includes, выражения), по-прежнему возвращаются без спецификации.
+func WithRecovery() Option {
+	return func(o *options) {
+		o.recover = true
+	}
+}
+
+// recoverySections — разделы-списки, элементы которых разбираются по одному.
+var recoverySections = map[string]string{
+	"providers":    "провайдер",
+	"components":   "компонент",
+	"dependencies": "зависимость",
+}
+
+// decodeRecovering разбирает документ root в raw по частям и возвращает
+// ошибки элементов, которые пришлось пропустить. Позиции разобранных
+// элементов заполняются сразу: индексы в raw не совпадают с индексами
+// в документе.
+func (o *options) decodeRecovering(root *yaml.Node, raw *rawSpec) ValidationErrors {
+	d := &recoveringDecoder{o: o}
+	body := resolveAlias(root)
+	if body == nil || body.Kind != yaml.MappingNode {
+		var discard rawSpec
+		d.decode(root, &discard, yamlPath{}, "документ")
+		return d.errs
+	}
+
+	rawValue := reflect.ValueOf(raw).Elem()
+	for i := 0; i+1 < len(body.Content); i += 2 {
+		key, value := body.Content[i], body.Content[i+1]
+		if what, exists := recoverySections[key.Value]; exists && resolveAlias(value).Kind == yaml.SequenceNode {
+			d.section(raw, key.Value, what, sectionItems(body, key.Value))
+			continue
+		}
+
+		f, known := yamlFieldByKey(rawValue.Type(), key.Value)
+		if !known {
+			continue
+		}
+		// Ключ разбирается отдельно, чтобы ошибка не затронула остальные
+		var part rawSpec
+		single := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Content: []*yaml.Node{key, value}}
+		if d.decode(single, &part, yamlPath{key.Value}, "раздел "+key.Value) {
+			rawValue.FieldByIndex(f.Index).Set(reflect.ValueOf(part).FieldByIndex(f.Index))
+		}
+	}
+	return d.errs
+}
+
+type recoveringDecoder struct {
+	o    *options
+	errs ValidationErrors
+}
+
+// section разбирает элементы раздела name по одному.
+func (d *recoveringDecoder) section(raw *rawSpec, name, what string, items []*yaml.Node) {
+	for i, item := range items {
+		path := yamlPath{name, i}
+		label := what + " " + path.String()
+		if n := scalarValue(mappingValue(item, "name")); n != "" {
+			label = fmt.Sprintf("%s %q", what, n)
+		}
+
+		switch name {
+		case "providers":
+			var p Provider
+			if d.decode(item, &p, path, label) {
+				p.setPositions(item, d.o.fileOf)
+				raw.Providers = append(raw.Providers, p)
+			}
+		case "components":
+			var r Resource
+			if d.decode(item, &r, path, label) {
+				r.setPositions(item, d.o.fileOf)
+				raw.Resources = append(raw.Resources, r)
+			}
+		default: // dependencies
+			var dep Dependency
+			if d.decode(item, &dep, path, label) {
+				dep.Pos = nodePosition(d.o.fileOf(item), item)
+				raw.Dependencies = append(raw.Dependencies, dep)
+			}
+		}
+	}
+}
+
+// decode разбирает узел n в out. При ошибке запоминает диагностику
+// и возвращает false.
+func (d *recoveringDecoder) decode(n *yaml.Node, out interface{}, path yamlPath, label string) bool {
+	err := n.Decode(out)
+	if err == nil {
+		return true
+	}
+
+	at := n
+	if len(path) == 1 {
+		// Для ключа верхнего уровня указываем на сам ключ
+		at = n.Content[0]
+	}
+	d.errs = append(d.errs, &ValidationError{
+		Code:     CodeInvalidValue,
+		Message:  fmt.Sprintf("не удалось разобрать %s: %s", label, decodeErrorDetail(err)),
+		Path:     path.String(),
+		Position: nodePosition(d.o.fileOf(at), at),
+	})
+	return false
+}
+
+// decodeErrorDetail возвращает текст ошибки декодирования без префикса yaml:
+// и с ошибками типов через точку с запятой.
+func decodeErrorDetail(err error) string {
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		return strings.Join(typeErr.Errors, "; ")
+	}
+	return strings.TrimPrefix(err.Error(), "yaml: ")
+}
@@ -0,0 +1,166 @@
+package parser
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const malformedYAML = `openinfra: 1.1.0
+info:
+  title: lab
+providers:
+  - name: cloud
+    type: cloud
+    connection:
+      protocol: https
+      port: high
+  - name: local
+    type: hypervisor
+    capabilities:
+      - name: start
+        timeout: soon
+  - name: vbox
+    type: hypervisor
+components:
+  - name: vm
+    type: virtual_machine
+    provider: vbox
+    actions: start
+  - name: net
+    type: network
+    provider: vbox
+  - [not, a, component]
+dependencies:
+  - component: vm
+    depends_on: net
+  - component: net
+    depends_on: []
+`
+
+func TestParseWithRecovery(t *testing.T) {
+	_, err := ParseBytes([]byte(malformedYAML))
+	require.Error(t, err)
+
+	spec, err := ParseBytes([]byte(malformedYAML), WithSource("spec.yaml"), WithRecovery())
+	require.NotNil(t, spec)
+	var errs ValidationErrors
+	require.True(t, errors.As(err, &errs))
+
+	// Разобранные элементы доступны, как обычно
+	assert.Equal(t, "lab", spec.Info.Title)
+	assert.Equal(t, []string{"vbox"}, spec.providerNames())
+	assert.Equal(t, []string{"net"}, spec.resourceNames())
+	assert.Equal(t, Position{File: "spec.yaml", Line: 15, Column: 5}, spec.Providers["vbox"].Pos)
+	assert.Equal(t, Position{File: "spec.yaml", Line: 22, Column: 5}, spec.Resources["net"].Pos)
+	require.Len(t, spec.Dependencies, 1)
+	assert.Equal(t, "net", spec.Dependencies[0].Resource)
+	assert.Equal(t, Position{File: "spec.yaml", Line: 29, Column: 5}, spec.Dependencies[0].Pos)
+
+	type diagnostic struct {
+		Path     string
+		Position string
+		Message  string
+	}
+	var got []diagnostic
+	for _, e := range errs {
+		assert.Equal(t, CodeInvalidValue, e.Code)
+		got = append(got, diagnostic{e.Path, e.Position.String(), e.Message})
+	}
+	assert.Equal(t, []diagnostic{
+		{"providers[0]", "spec.yaml:5:5", "не удалось разобрать провайдер \"cloud\": line 9: cannot unmarshal !!str `high` into int"},
+		{"providers[1]", "spec.yaml:10:5", "не удалось разобрать провайдер \"local\": line 14: cannot unmarshal !!str `soon` into time.Duration"},
+		{"components[0]", "spec.yaml:18:5", "не удалось разобрать компонент \"vm\": line 21: cannot unmarshal !!str `start` into []parser.Action"},
+		{"components[2]", "spec.yaml:25:5", "не удалось разобрать компонент components[2]: line 25: cannot unmarshal !!seq into parser.Resource"},
+		{"dependencies[0]", "spec.yaml:27:5", "не удалось разобрать зависимость dependencies[0]: line 28: cannot unmarshal !!str `net` into []string"},
+	}, got)
+}
+
+func TestParseWithRecoveryTopLevel(t *testing.T) {
+	spec, err := ParseBytes([]byte("openinfra: 1.1.0\ninfo: [lab]\ncomponents: {name: vm}\nvariables:\n  zone: a\n"),
+		WithRecovery())
+	require.NotNil(t, spec)
+	assert.Equal(t, "1.1.0", spec.Version)
+	assert.Equal(t, map[string]interface{}{"zone": "a"}, spec.Variables)
+
+	var errs ValidationErrors
+	require.True(t, errors.As(err, &errs))
+	require.Len(t, errs, 2)
+	assert.Equal(t, "info", errs[0].Path)
+	assert.Equal(t, Position{Line: 2, Column: 1}, errs[0].Position)
+	assert.Equal(t, "components", errs[1].Path)
+	assert.Contains(t, errs[1].Message, "не удалось разобрать раздел components")
+
+	// Корректный документ разбирается без ошибок
+	spec, err = ParseBytes([]byte(mergeBaseYAML), WithRecovery())
+	require.NoError(t, err)
+	plain, err := ParseBytes([]byte(mergeBaseYAML))
+	require.NoError(t, err)
+	assert.Equal(t, stripSource(plain), stripSource(spec))
+
+	// Синтаксическая ошибка не даёт спецификации
+	spec, err = ParseBytes([]byte("providers: [\n"), WithRecovery())
+	assert.Nil(t, spec)
+	assert.Error(t, err)
+}
+
+func TestParseWithRecoveryStrict(t *testing.T) {
+	spec, err := ParseBytes([]byte(`openinfra: 1.1.0
+components:
+  - name: vm
+    provider: p
+    tpye: virtual_machine
+  - name: net
+    provider: p
+    actions: start
+`), WithStrict(), WithRecovery())
+	require.NotNil(t, spec)
+	assert.Contains(t, spec.Resources, "vm")
+
+	var errs ValidationErrors
+	require.True(t, errors.As(err, &errs))
+	require.Len(t, errs, 2)
+	assert.Equal(t, CodeUnknownField, errs[0].Code)
+	assert.Equal(t, CodeInvalidValue, errs[1].Code)
+}
+
+func TestParseAllWithRecovery(t *testing.T) {
+	specs, err := ParseAll([]byte(streamYAML+"---\ncomponents:\n  - name: db\n  - name: [x]\n"), WithRecovery())
+	require.Len(t, specs, 3)
+	assert.Equal(t, []string{"db"}, specs[2].resourceNames())
+
+	var errs ValidationErrors
+	require.True(t, errors.As(err, &errs))
+	require.Len(t, errs, 1)
+	assert.Equal(t, 21, errs[0].Line)
+}
+
+func TestValidateRecoveredSpec(t *testing.T) {
+	spec, err := ParseBytes([]byte(`openinfra: 1.1.0
+providers:
+  - name: cloud
+    type: cloud
+    connection:
+      port: high
+components:
+  - name: vm
+    type: virtual_machine
+    provider: cloud
+    actions: start
+  - name: net
+    type: network
+    provider: cloud
+    dependencies:
+      - depends_on: [vm, ghost]
+`), WithRecovery())
+	require.Error(t, err)
+	require.NotNil(t, spec)
+
+	// Ссылки на пропущенные cloud и vm уже сообщены при разборе
+	errs := Validate(spec)
+	require.Len(t, errs, 1)
+	assert.Equal(t, CodeUnknownComponent, errs[0].Code)
+	assert.Equal(t, "components[1].dependencies[0].depends_on[1]", errs[0].Path)
+}
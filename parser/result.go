@@ -0,0 +1,178 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// CapabilityResult — ответ провайдера на вызов возможности.
+type CapabilityResult struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	// JSON — декодированное тело ответа, если оно в формате JSON.
+	// Если у возможности задана схема ответа, поля приведены к её типам.
+	JSON interface{}
+}
+
+// String возвращает тело ответа как строку.
+func (r *CapabilityResult) String() string {
+	return string(r.Body)
+}
+
+// Decode декодирует JSON-тело ответа в v.
+func (r *CapabilityResult) Decode(v interface{}) error {
+	if err := json.Unmarshal(r.Body, v); err != nil {
+		return fmt.Errorf("ошибка при декодировании ответа: %w", err)
+	}
+	return nil
+}
+
+// Object возвращает ответ как объект JSON.
+func (r *CapabilityResult) Object() (map[string]interface{}, bool) {
+	obj, ok := r.JSON.(map[string]interface{})
+	return obj, ok
+}
+
+// CapabilityError возвращается, если провайдер ответил статусом не из 2xx.
+type CapabilityError struct {
+	Provider   string
+	Capability string
+	StatusCode int
+	Status     string
+	Body       []byte
+}
+
+func (e *CapabilityError) Error() string {
+	msg := fmt.Sprintf("возможность %s провайдера %s вернула статус %s", e.Capability, e.Provider, e.Status)
+	if body := strings.TrimSpace(string(e.Body)); body != "" {
+		if len(body) > 200 {
+			body = body[:200] + "..."
+		}
+		msg += ": " + body
+	}
+	return msg
+}
+
+// decodeBody декодирует JSON-тело ответа. Без схемы тело декодируется,
+// только если ответ помечен как JSON, и ошибки декодирования игнорируются.
+func (r *CapabilityResult) decodeBody(schema *ResponseSchema) error {
+	if len(r.Body) == 0 {
+		return nil
+	}
+	if schema == nil {
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if mediaType != "application/json" {
+			return nil
+		}
+		var v interface{}
+		if json.Unmarshal(r.Body, &v) == nil {
+			r.JSON = v
+		}
+		return nil
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(r.Body, &v); err != nil {
+		return fmt.Errorf("ошибка при декодировании ответа: %w", err)
+	}
+	typed, err := schema.apply(v)
+	if err != nil {
+		return err
+	}
+	r.JSON = typed
+	return nil
+}
+
+// apply проверяет значение по схеме и приводит поля к объявленным типам.
+func (s *ResponseSchema) apply(v interface{}) (interface{}, error) {
+	switch s.Type {
+	case "array":
+		items, ok := v.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("ошибка: ожидался массив в ответе, получено %s", jsonTypeName(v))
+		}
+		for i, item := range items {
+			obj, err := s.applyObject(item)
+			if err != nil {
+				return nil, fmt.Errorf("элемент %d: %w", i, err)
+			}
+			items[i] = obj
+		}
+		return items, nil
+	case "", "object":
+		return s.applyObject(v)
+	default:
+		return nil, fmt.Errorf("ошибка: неизвестный тип ответа %q", s.Type)
+	}
+}
+
+func (s *ResponseSchema) applyObject(v interface{}) (map[string]interface{}, error) {
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("ошибка: ожидался объект в ответе, получено %s", jsonTypeName(v))
+	}
+	for _, field := range s.Fields {
+		value, exists := obj[field.Name]
+		if !exists {
+			if field.Required {
+				return nil, fmt.Errorf("ошибка: в ответе отсутствует поле %s", field.Name)
+			}
+			continue
+		}
+		typed, ok := coerceJSON(value, field.Type)
+		if !ok {
+			return nil, fmt.Errorf("ошибка: поле %s ответа должно иметь тип %s, получено %s",
+				field.Name, field.Type, jsonTypeName(value))
+		}
+		obj[field.Name] = typed
+	}
+	return obj, nil
+}
+
+// coerceJSON приводит значение, декодированное из JSON, к типу typ.
+func coerceJSON(v interface{}, typ string) (interface{}, bool) {
+	switch typ {
+	case "", "any":
+		return v, true
+	case "integer":
+		return toInt(v)
+	case "number":
+		return toFloat(v)
+	case "string":
+		s, ok := v.(string)
+		return s, ok
+	case "boolean":
+		b, ok := v.(bool)
+		return b, ok
+	case "array":
+		a, ok := v.([]interface{})
+		return a, ok
+	case "object":
+		o, ok := v.(map[string]interface{})
+		return o, ok
+	}
+	return v, true
+}
+
+// jsonTypeName возвращает название JSON-типа значения для сообщений.
+func jsonTypeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64, json.Number:
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
@@ -0,0 +1,105 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newJSONServer(status int, body string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Header().Set("X-Request-Id", "42")
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+}
+
+func TestCapabilityResultJSON(t *testing.T) {
+	ts := newJSONServer(http.StatusCreated, `{"id": "vm-1", "cpu": 2, "load": 0.5}`)
+	defer ts.Close()
+
+	provider := Provider{
+		Name:       "vbox",
+		Connection: Connection{Endpoint: ts.URL},
+		Capabilities: []Capability{
+			{Name: "create_vm", Method: "POST", Endpoint: "/vms"},
+			{Name: "create_vm_typed", Method: "POST", Endpoint: "/vms", Response: &ResponseSchema{
+				Type: "object",
+				Fields: []Parameter{
+					{Name: "id", Type: "string", Required: true},
+					{Name: "cpu", Type: "integer"},
+				},
+			}},
+		},
+	}
+
+	result, err := provider.ExecuteCapabilityContext(context.Background(), "create_vm", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, result.StatusCode)
+	assert.Equal(t, "42", result.Header.Get("X-Request-Id"))
+	obj, ok := result.Object()
+	assert.True(t, ok)
+	assert.Equal(t, 2.0, obj["cpu"])
+
+	result, err = provider.ExecuteCapabilityContext(context.Background(), "create_vm_typed", nil)
+	assert.NoError(t, err)
+	obj, _ = result.Object()
+	assert.Equal(t, map[string]interface{}{"id": "vm-1", "cpu": 2, "load": 0.5}, obj)
+
+	var decoded struct {
+		ID  string `json:"id"`
+		CPU int    `json:"cpu"`
+	}
+	assert.NoError(t, result.Decode(&decoded))
+	assert.Equal(t, "vm-1", decoded.ID)
+}
+
+func TestCapabilityResultSchemaMismatch(t *testing.T) {
+	ts := newJSONServer(http.StatusOK, `[{"id": 1}]`)
+	defer ts.Close()
+
+	provider := Provider{
+		Name:       "vbox",
+		Connection: Connection{Endpoint: ts.URL},
+		Capabilities: []Capability{
+			{Name: "list_vms", Method: "GET", Endpoint: "/vms", Response: &ResponseSchema{
+				Type:   "array",
+				Fields: []Parameter{{Name: "id", Type: "string"}},
+			}},
+		},
+	}
+
+	result, err := provider.ExecuteCapabilityContext(context.Background(), "list_vms", nil)
+	assert.EqualError(t, err, "возможность list_vms провайдера vbox: элемент 0: ошибка: поле id ответа должно иметь тип string, получено number")
+	assert.Equal(t, http.StatusOK, result.StatusCode)
+}
+
+func TestCapabilityError(t *testing.T) {
+	ts := newJSONServer(http.StatusInternalServerError, `{"error": "boom"}`)
+	defer ts.Close()
+
+	provider := Provider{
+		Name:       "vbox",
+		Connection: Connection{Endpoint: ts.URL},
+		Capabilities: []Capability{
+			{Name: "start_vm", Method: "POST", Endpoint: "/vms/start"},
+		},
+	}
+
+	result, err := provider.ExecuteCapabilityContext(context.Background(), "start_vm", nil)
+	var capErr *CapabilityError
+	assert.True(t, errors.As(err, &capErr))
+	assert.Equal(t, http.StatusInternalServerError, capErr.StatusCode)
+	assert.Equal(t, `возможность start_vm провайдера vbox вернула статус 500 Internal Server Error: {"error": "boom"}`, err.Error())
+	assert.Equal(t, http.StatusInternalServerError, result.StatusCode)
+	assert.Nil(t, result.JSON)
+
+	body, err := provider.ExecuteCapability("start_vm", nil)
+	assert.ErrorAs(t, err, &capErr)
+	assert.Equal(t, `{"error": "boom"}`, body)
+}
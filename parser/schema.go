@@ -0,0 +1,359 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SchemaID — идентификатор JSON Schema формата OpenInfra.
+const SchemaID = "https://github.com/Ilya-Guyduk/openinfra/schema/openinfra.schema.json"
+
+// CodeSchema — код ошибок проверки документа по JSON Schema.
+const CodeSchema ErrorCode = "schema"
+
+// requiredFields перечисляет обязательные ключи для типов спецификации.
+// Остальная часть схемы строится по полям структур из types.go.
+var requiredFields = map[reflect.Type][]string{
+	reflect.TypeOf(rawSpec{}):    {"openinfra"},
+	reflect.TypeOf(Provider{}):   {"name", "type"},
+	reflect.TypeOf(Capability{}): {"name"},
+	reflect.TypeOf(Parameter{}):  {"name"},
+	reflect.TypeOf(Action{}):     {"name"},
+	reflect.TypeOf(Resource{}):   {"name", "type", "provider"},
+}
+
+// enumFields перечисляет допустимые значения отдельных полей.
+var enumFields = map[reflect.Type]map[string][]interface{}{
+	reflect.TypeOf(Parameter{}): {
+		"type": {TypeString, TypeInteger, TypeNumber, TypeBoolean, TypeArray, TypeObject, TypeEnum},
+		"in":   {ParamInPath, ParamInQuery, ParamInHeader, ParamInBody},
+	},
+	reflect.TypeOf(ResponseSchema{}): {
+		"type": {"object", "array"},
+	},
+}
+
+// extensionKeys — ключи расширений вида x-owner, допустимые в любом объекте.
+const extensionKeys = "^x-"
+
+//go:generate go run ../cmd/openinfra schema -o ../schema/openinfra.schema.json
+
+// JSONSchema возвращает JSON Schema (draft 2020-12) формата OpenInfra,
+// построенную по типам из types.go.
+func JSONSchema() map[string]interface{} {
+	g := &schemaGenerator{defs: make(map[string]interface{})}
+	root := g.structSchema(reflect.TypeOf(rawSpec{}))
+	root["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	root["$id"] = SchemaID
+	root["title"] = "OpenInfra"
+	root["$defs"] = g.defs
+	return root
+}
+
+// JSONSchemaBytes возвращает JSON Schema в виде отформатированного JSON.
+func JSONSchemaBytes() ([]byte, error) {
+	data, err := json.MarshalIndent(JSONSchema(), "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при генерации JSON Schema: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+type schemaGenerator struct {
+	defs map[string]interface{}
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func (g *schemaGenerator) typeSchema(t reflect.Type) map[string]interface{} {
+	if t == durationType {
+		// yaml.v3 читает time.Duration только из строки вида 30s
+		return map[string]interface{}{
+			"type":    "string",
+			"pattern": `^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`,
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return g.typeSchema(t.Elem())
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": g.typeSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.typeSchema(t.Elem())}
+	case reflect.Struct:
+		// Именованные типы выносятся в $defs, анонимные описываются на месте
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		if _, exists := g.defs[t.Name()]; !exists {
+			g.defs[t.Name()] = nil // защита от рекурсии
+			g.defs[t.Name()] = g.structSchema(t)
+		}
+		return map[string]interface{}{"$ref": "#/$defs/" + t.Name()}
+	}
+	// interface{} — любое значение
+	return map[string]interface{}{}
+}
+
+func (g *schemaGenerator) structSchema(t reflect.Type) map[string]interface{} {
+	props := make(map[string]interface{})
+	for _, f := range yamlFields(t) {
+		s := g.typeSchema(f.Type)
+		if values, exists := enumFields[t][f.Key]; exists {
+			s["enum"] = values
+		}
+		props[f.Key] = s
+	}
+
+	schema := map[string]interface{}{
+		"type":                 "object",
+		"properties":           props,
+		"additionalProperties": false,
+		"patternProperties":    map[string]interface{}{extensionKeys: map[string]interface{}{}},
+	}
+	if required := requiredFields[t]; len(required) > 0 {
+		list := make([]interface{}, len(required))
+		for i, r := range required {
+			list[i] = r
+		}
+		schema["required"] = list
+	}
+	return schema
+}
+
+// ValidateSchema проверяет YAML-документ по JSON Schema формата и
+// возвращает ошибки с путём и позицией проблемного узла. Файлы из includes
+// и ссылки $ref разрешаются так же, как при разборе, поэтому позиции
+// указывают на файл, в котором находится узел. Документы прежних версий
+// формата сначала приводятся к текущей, как при разборе, а неподдерживаемая
+// версия возвращается ошибкой. Документ может быть в формате JSON;
+// в потоке YAML-документов проверяется каждый.
+func ValidateSchema(data []byte, opts ...Option) (ValidationErrors, error) {
+	o := newOptions(opts)
+
+	docs, err := decodeDocuments(data)
+	if err != nil {
+		return nil, o.yamlError(err)
+	}
+	if len(docs) == 0 {
+		docs = []*yaml.Node{{}}
+	}
+	var errs ValidationErrors
+	for i, root := range docs {
+		do := *o
+		do.files = nil
+		migrated, err := do.prepareSchemaDocument(root)
+		if err != nil {
+			if len(docs) > 1 {
+				return nil, &DocumentError{Index: i, Err: err}
+			}
+			return nil, err
+		}
+		errs = append(errs, validateSchema(migrated, do.fileOf)...)
+	}
+	return errs, nil
+}
+
+// prepareSchemaDocument подключает includes документа root и приводит
+// его к текущей версии. Предупреждения об устаревшей форме не нужны:
+// схема описывает только текущую.
+func (o *options) prepareSchemaDocument(root *yaml.Node) (*yaml.Node, error) {
+	if err := o.resolveIncludes(root); err != nil {
+		return nil, err
+	}
+	migrated, _, err := o.migrate(root, true)
+	return migrated, err
+}
+
+// ValidateSchemaNode проверяет дерево узлов по JSON Schema формата.
+// file используется в позициях ошибок.
+func ValidateSchemaNode(root *yaml.Node, file string) ValidationErrors {
+	return validateSchema(root, func(*yaml.Node) string { return file })
+}
+
+func validateSchema(root *yaml.Node, fileOf func(*yaml.Node) string) ValidationErrors {
+	schema := JSONSchema()
+	v := &schemaValidator{
+		defs:   schema["$defs"].(map[string]interface{}),
+		fileOf: fileOf,
+	}
+	n := resolveAlias(root)
+	if n == nil {
+		n = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	}
+	v.validate(n, schema, yamlPath{})
+	return v.errs
+}
+
+type schemaValidator struct {
+	defs   map[string]interface{}
+	fileOf func(*yaml.Node) string
+	errs   ValidationErrors
+}
+
+func (v *schemaValidator) report(n *yaml.Node, path yamlPath, format string, args ...interface{}) {
+	v.errs = append(v.errs, &ValidationError{
+		Code:     CodeSchema,
+		Message:  fmt.Sprintf(format, args...),
+		Path:     path.String(),
+		Position: nodePosition(v.fileOf(n), n),
+	})
+}
+
+func (v *schemaValidator) validate(n *yaml.Node, schema map[string]interface{}, path yamlPath) {
+	n = resolveAlias(n)
+
+	if ref, ok := schema["$ref"].(string); ok {
+		def, _ := v.defs[strings.TrimPrefix(ref, "#/$defs/")].(map[string]interface{})
+		v.validate(n, def, path)
+		return
+	}
+
+	if isExpression(n) {
+		// Тип значения ${var.name} известен только после подстановки
+		return
+	}
+
+	if types, ok := schema["type"]; ok && !nodeMatchesType(n, types) {
+		v.report(n, path, "ожидался тип %s, получено %s", typeList(types), nodeTypeName(n))
+		return
+	}
+
+	if values, ok := schema["enum"].([]interface{}); ok && n.Kind == yaml.ScalarNode {
+		allowed := make([]string, len(values))
+		found := false
+		for i, e := range values {
+			allowed[i] = fmt.Sprint(e)
+			found = found || allowed[i] == n.Value
+		}
+		if !found {
+			v.report(n, path, "значение %q не входит в список допустимых: %s", n.Value, strings.Join(allowed, ", "))
+		}
+	}
+
+	if pattern, ok := schema["pattern"].(string); ok && n.Kind == yaml.ScalarNode && n.ShortTag() == "!!str" {
+		if !regexp.MustCompile(pattern).MatchString(n.Value) {
+			v.report(n, path, "значение %q не соответствует шаблону %s", n.Value, pattern)
+		}
+	}
+
+	switch n.Kind {
+	case yaml.MappingNode:
+		v.validateObject(n, schema, path)
+	case yaml.SequenceNode:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range n.Content {
+				v.validate(item, items, path.with(i))
+			}
+		}
+	}
+}
+
+func (v *schemaValidator) validateObject(n *yaml.Node, schema map[string]interface{}, path yamlPath) {
+	props, _ := schema["properties"].(map[string]interface{})
+	patterns, _ := schema["patternProperties"].(map[string]interface{})
+
+	if required, ok := schema["required"].([]interface{}); ok {
+		for _, r := range required {
+			if mappingValue(n, r.(string)) == nil {
+				v.report(n, path, "отсутствует обязательное поле %s", r)
+			}
+		}
+	}
+
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, value := n.Content[i], n.Content[i+1]
+		keyPath := path.with(key.Value)
+
+		if s, ok := props[key.Value].(map[string]interface{}); ok {
+			v.validate(value, s, keyPath)
+			continue
+		}
+
+		matched := false
+		for pattern, s := range patterns {
+			if regexp.MustCompile(pattern).MatchString(key.Value) {
+				v.validate(value, s.(map[string]interface{}), keyPath)
+				matched = true
+			}
+		}
+		if matched {
+			continue
+		}
+
+		switch extra := schema["additionalProperties"].(type) {
+		case bool:
+			if !extra {
+				v.report(key, keyPath, "неизвестное поле %s", key.Value)
+			}
+		case map[string]interface{}:
+			v.validate(value, extra, keyPath)
+		}
+	}
+}
+
+// nodeMatchesType проверяет соответствие узла типу (или списку типов) схемы.
+func nodeMatchesType(n *yaml.Node, types interface{}) bool {
+	list, ok := types.([]interface{})
+	if !ok {
+		list = []interface{}{types}
+	}
+	actual := nodeTypeName(n)
+	for _, t := range list {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// nodeTypeName возвращает JSON-тип узла.
+func nodeTypeName(n *yaml.Node) string {
+	switch n.Kind {
+	case yaml.MappingNode:
+		return "object"
+	case yaml.SequenceNode:
+		return "array"
+	}
+	switch n.ShortTag() {
+	case "!!int":
+		return "integer"
+	case "!!float":
+		return "number"
+	case "!!bool":
+		return "boolean"
+	case "!!null":
+		return "null"
+	}
+	return "string"
+}
+
+func typeList(types interface{}) string {
+	list, ok := types.([]interface{})
+	if !ok {
+		return fmt.Sprint(types)
+	}
+	names := make([]string, len(list))
+	for i, t := range list {
+		names[i] = fmt.Sprint(t)
+	}
+	sort.Strings(names)
+	return strings.Join(names, " или ")
+}
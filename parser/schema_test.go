@@ -0,0 +1,144 @@
+package parser
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONSchemaInSync(t *testing.T) {
+	want, err := JSONSchemaBytes()
+	require.NoError(t, err)
+
+	got, err := os.ReadFile("../schema/openinfra.schema.json")
+	require.NoError(t, err)
+	assert.Equal(t, string(want), string(got),
+		"schema/openinfra.schema.json устарел, выполните go generate ./parser")
+}
+
+func TestJSONSchemaStructure(t *testing.T) {
+	data, err := JSONSchemaBytes()
+	require.NoError(t, err)
+
+	var schema map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &schema))
+
+	assert.Equal(t, SchemaID, schema["$id"])
+	assert.Equal(t, []interface{}{"openinfra"}, schema["required"])
+
+	defs := schema["$defs"].(map[string]interface{})
+	for _, name := range []string{"Provider", "Resource", "Capability", "Parameter", "Authentication", "Dependency"} {
+		assert.Contains(t, defs, name)
+	}
+
+	resource := defs["Resource"].(map[string]interface{})
+	assert.Equal(t, []interface{}{"name", "type", "provider"}, resource["required"])
+	props := resource["properties"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"$ref": "#/$defs/Dependency"},
+		props["dependencies"].(map[string]interface{})["items"])
+}
+
+func TestValidateSchemaGenerated(t *testing.T) {
+	spec, err := ParseBytes([]byte(sampleYAML))
+	require.NoError(t, err)
+	data, err := GenerateYAML(spec)
+	require.NoError(t, err)
+
+	errs, err := ValidateSchema([]byte(data))
+	require.NoError(t, err)
+	assert.Empty(t, errs)
+}
+
+func TestValidateSchemaAgreesWithParser(t *testing.T) {
+	for _, timeout := range []string{"30s", "1m30s", "30", "soon"} {
+		doc := []byte(`openinfra: 1.1.0
+providers:
+  - name: p
+    type: t
+    capabilities:
+      - name: start
+        timeout: ` + timeout + "\n")
+
+		errs, err := ValidateSchema(doc)
+		require.NoError(t, err)
+		_, parseErr := ParseBytes(doc)
+		assert.Equal(t, parseErr != nil, len(errs) > 0, "timeout: %s: схема %v, разбор %v", timeout, errs, parseErr)
+	}
+}
+
+func TestValidateSchemaLegacyVersion(t *testing.T) {
+	// Документ 1.0 приводится к текущей версии, как при разборе
+	_, err := ParseBytes([]byte(legacyYAML))
+	require.NoError(t, err)
+	errs, err := ValidateSchema([]byte(legacyYAML), WithSource("legacy.yaml"))
+	require.NoError(t, err)
+	assert.Empty(t, errs)
+
+	_, err = ValidateSchema([]byte("openinfra: 2.0.0\n"), WithSource("new.yaml"))
+	assert.EqualError(t, err,
+		"ошибка в поле openinfra файла new.yaml: версия 2.0.0 не поддерживается, поддерживаемые версии: 1.0, 1.1")
+}
+
+func TestValidateSchemaSample(t *testing.T) {
+	// В примере у действий указаны endpoint и parameters, которых нет
+	// в типе Action: парсер их молча отбрасывает, а схема сообщает о них.
+	errs, err := ValidateSchema([]byte(sampleYAML))
+	require.NoError(t, err)
+	require.NotEmpty(t, errs)
+	for _, e := range errs {
+		assert.Regexp(t, `^components\[\d\]\.actions\[\d\]\.(endpoint|parameters)$`, e.Path)
+	}
+}
+
+func TestValidateSchemaErrors(t *testing.T) {
+	doc := `openinfra: 1.0.0
+x-owner: team
+providers:
+  - name: vbox
+    type: virtualbox
+    conection: {}
+    capabilities:
+      - name: start
+        timeout: soon
+        parameters:
+          - name: id
+            type: uuid
+components:
+  - name: vm
+    provider: vbox
+    properties: [cpu]
+`
+	errs, err := ValidateSchema([]byte(doc), WithSource("spec.yaml"))
+	require.NoError(t, err)
+
+	type short struct {
+		Path   string
+		Line   int
+		Column int
+	}
+	var got []short
+	for _, e := range errs {
+		assert.Equal(t, CodeSchema, e.Code)
+		assert.Equal(t, "spec.yaml", e.File)
+		got = append(got, short{e.Path, e.Line, e.Column})
+	}
+	assert.Equal(t, []short{
+		{"providers[0].conection", 6, 5},
+		{"providers[0].capabilities[0].timeout", 9, 18},
+		{"providers[0].capabilities[0].parameters[0].type", 12, 19},
+		{"components[0]", 14, 5},
+		{"components[0].properties", 16, 17},
+	}, got)
+
+	assert.Contains(t, errs[0].Error(), "spec.yaml:6:5: неизвестное поле conection")
+	assert.Contains(t, errs[3].Message, "отсутствует обязательное поле type")
+	assert.Contains(t, errs[4].Message, "ожидался тип object, получено array")
+}
+
+func TestValidateSchemaInvalidYAML(t *testing.T) {
+	_, err := ValidateSchema([]byte("openinfra: [1.0"), WithSource("bad.yaml"))
+	assert.ErrorContains(t, err, "некорректное форматирование YAML в файле bad.yaml")
+}
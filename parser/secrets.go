@@ -0,0 +1,187 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// SecretResolver получает значение секрета по ссылке.
+type SecretResolver interface {
+	ResolveSecret(ctx context.Context, ref string) (string, error)
+}
+
+// SecretResolverFunc позволяет использовать функцию как SecretResolver.
+type SecretResolverFunc func(ctx context.Context, ref string) (string, error)
+
+func (f SecretResolverFunc) ResolveSecret(ctx context.Context, ref string) (string, error) {
+	return f(ctx, ref)
+}
+
+var (
+	secretMu        sync.RWMutex
+	secretResolvers = map[string]SecretResolver{}
+)
+
+// localSecretResolvers читают секреты с машины, на которой выполняется
+// запрос: из переменных окружения (${env:NAME}) и файлов (${file:path}).
+// Спецификация выбирает и ссылку, и адрес, куда уйдёт значение, поэтому
+// они работают только для доверенных спецификаций, см. WithLocalSecrets.
+var localSecretResolvers = map[string]SecretResolver{
+	"env":  SecretResolverFunc(envSecret),
+	"file": SecretResolverFunc(fileSecret),
+}
+
+type localSecretsKey struct{}
+
+// LocalSecretsContext возвращает контекст, в котором ссылки на секреты
+// ${env:NAME} и ${file:path} разрешаются из переменных окружения и файлов
+// (см. WithLocalSecrets). Нужен тем, кто вызывает Authentication.Resolve
+// или ResolveSecret напрямую.
+func LocalSecretsContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, localSecretsKey{}, true)
+}
+
+func localSecretsEnabled(ctx context.Context) bool {
+	enabled, _ := ctx.Value(localSecretsKey{}).(bool)
+	return enabled
+}
+
+// RegisterSecretResolver регистрирует SecretResolver для схемы scheme.
+// После этого значения вида ${scheme:ref} и scheme:ref в Authentication
+// считаются ссылками и разрешаются через него.
+func RegisterSecretResolver(scheme string, r SecretResolver) {
+	secretMu.Lock()
+	defer secretMu.Unlock()
+	secretResolvers[scheme] = r
+}
+
+func lookupSecretResolver(scheme string) (SecretResolver, bool) {
+	secretMu.RLock()
+	defer secretMu.RUnlock()
+	r, exists := secretResolvers[scheme]
+	return r, exists
+}
+
+// parseSecretRef разбирает ссылку ${scheme:ref} или scheme:ref.
+// Строка считается ссылкой, если схема зарегистрирована. Схемы env
+// и file без фигурных скобок (env:NAME) считаются ссылкой, только если
+// локальные секреты включены в ctx: иначе это обычное значение.
+func parseSecretRef(ctx context.Context, s string) (scheme, ref string, ok bool) {
+	body := s
+	braced := strings.HasPrefix(s, "${") && strings.HasSuffix(s, "}")
+	if braced {
+		body = s[2 : len(s)-1]
+	}
+	scheme, ref, found := strings.Cut(body, ":")
+	if !found || scheme == "" || ref == "" {
+		return "", "", false
+	}
+	if _, exists := lookupSecretResolver(scheme); exists {
+		return scheme, ref, true
+	}
+	if _, local := localSecretResolvers[scheme]; local && (braced || localSecretsEnabled(ctx)) {
+		return scheme, ref, true
+	}
+	return "", "", false
+}
+
+// IsSecretRef сообщает, является ли значение ссылкой на секрет.
+func IsSecretRef(s string) bool {
+	_, _, ok := parseSecretRef(context.Background(), s)
+	return ok
+}
+
+// ResolveSecret возвращает значение секрета, если s — ссылка, и саму s иначе.
+// Ссылки ${env:NAME} и ${file:path} без LocalSecretsContext — ошибка.
+func ResolveSecret(ctx context.Context, s string) (string, error) {
+	scheme, ref, ok := parseSecretRef(ctx, s)
+	if !ok {
+		return s, nil
+	}
+	r, exists := lookupSecretResolver(scheme)
+	if !exists {
+		if !localSecretsEnabled(ctx) {
+			return "", fmt.Errorf("ошибка при получении секрета %s: секреты из переменных окружения "+
+				"и файлов не включены (см. WithLocalSecrets)", s)
+		}
+		r = localSecretResolvers[scheme]
+	}
+	value, err := r.ResolveSecret(ctx, ref)
+	if err != nil {
+		return "", fmt.Errorf("ошибка при получении секрета %s: %w", s, err)
+	}
+	return value, nil
+}
+
+func envSecret(_ context.Context, name string) (string, error) {
+	value, exists := os.LookupEnv(name)
+	if !exists {
+		return "", fmt.Errorf("переменная окружения %s не задана", name)
+	}
+	return value, nil
+}
+
+func fileSecret(_ context.Context, path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// Resolve возвращает копию Authentication, в которой ссылки на секреты
+// заменены их значениями. Исходная структура не меняется, поэтому
+// при генерации YAML сохраняются ссылки, а не секреты.
+func (a Authentication) Resolve(ctx context.Context) (Authentication, error) {
+	var errs []error
+	for _, field := range []*string{&a.Username, &a.Password, &a.APIKey, &a.Token, &a.ClientID, &a.ClientSecret, &a.Secret} {
+		value, err := ResolveSecret(ctx, *field)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		*field = value
+	}
+	return a, errors.Join(errs...)
+}
+
+// String возвращает описание без секретов: значения password, api_key,
+// token, client_secret и secret скрываются, ссылки на секреты показываются.
+func (a Authentication) String() string {
+	var parts []string
+	add := func(name, value string, secret bool) {
+		if value == "" {
+			return
+		}
+		if secret && !IsSecretRef(value) {
+			value = "***"
+		}
+		parts = append(parts, name+": "+value)
+	}
+
+	add("method", a.Method, false)
+	add("username", a.Username, false)
+	add("password", a.Password, true)
+	add("api_key", a.APIKey, true)
+	add("token", a.Token, true)
+	add("header", a.Header, false)
+	add("param", a.Param, false)
+	add("token_url", a.TokenURL, false)
+	add("client_id", a.ClientID, false)
+	add("client_secret", a.ClientSecret, true)
+	if len(a.Scopes) > 0 {
+		parts = append(parts, "scopes: ["+strings.Join(a.Scopes, ", ")+"]")
+	}
+	add("secret", a.Secret, true)
+
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+// GoString скрывает секреты и при выводе через %#v.
+func (a Authentication) GoString() string {
+	return "parser.Authentication" + a.String()
+}
@@ -0,0 +1,130 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveSecret(t *testing.T) {
+	t.Setenv("OPENINFRA_TEST_PASS", "from-env")
+
+	secretFile := filepath.Join(t.TempDir(), "key")
+	assert.NoError(t, os.WriteFile(secretFile, []byte("from-file\n"), 0600))
+
+	ctx := LocalSecretsContext(context.Background())
+	for _, tt := range []struct {
+		in, want string
+	}{
+		{"${env:OPENINFRA_TEST_PASS}", "from-env"},
+		{"env:OPENINFRA_TEST_PASS", "from-env"},
+		{"file:" + secretFile, "from-file"},
+		{"${file:" + secretFile + "}", "from-file"},
+		{"plain", "plain"},
+		{"unknown:scheme", "unknown:scheme"},
+		{"", ""},
+	} {
+		got, err := ResolveSecret(ctx, tt.in)
+		assert.NoError(t, err, tt.in)
+		assert.Equal(t, tt.want, got, tt.in)
+	}
+
+	_, err := ResolveSecret(ctx, "${env:OPENINFRA_TEST_MISSING}")
+	assert.EqualError(t, err, "ошибка при получении секрета ${env:OPENINFRA_TEST_MISSING}: переменная окружения OPENINFRA_TEST_MISSING не задана")
+}
+
+func TestLocalSecretsOffByDefault(t *testing.T) {
+	t.Setenv("OPENINFRA_TEST_PASS", "from-env")
+	secretFile := filepath.Join(t.TempDir(), "key")
+	assert.NoError(t, os.WriteFile(secretFile, []byte("from-file\n"), 0600))
+
+	ctx := context.Background()
+	for _, ref := range []string{"${env:OPENINFRA_TEST_PASS}", "${file:" + secretFile + "}"} {
+		_, err := ResolveSecret(ctx, ref)
+		assert.EqualError(t, err, "ошибка при получении секрета "+ref+": секреты из переменных окружения "+
+			"и файлов не включены (см. WithLocalSecrets)")
+	}
+
+	// Без скобок это обычные значения, а не ссылки
+	for _, literal := range []string{"env:OPENINFRA_TEST_PASS", "file:" + secretFile} {
+		got, err := ResolveSecret(ctx, literal)
+		assert.NoError(t, err)
+		assert.Equal(t, literal, got)
+	}
+}
+
+func TestSecretResolverPlugin(t *testing.T) {
+	RegisterSecretResolver("vault", SecretResolverFunc(func(_ context.Context, ref string) (string, error) {
+		return "vault-" + ref, nil
+	}))
+	defer func() {
+		secretMu.Lock()
+		delete(secretResolvers, "vault")
+		secretMu.Unlock()
+	}()
+
+	auth, err := Authentication{Method: AuthBearer, Token: "${vault:kv/token}"}.Resolve(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "vault-kv/token", auth.Token)
+}
+
+func TestExecuteCapabilityResolvesSecrets(t *testing.T) {
+	t.Setenv("OPENINFRA_TEST_PASS", "s3cr3t")
+
+	var user, pass string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, _ = r.BasicAuth()
+	}))
+	defer ts.Close()
+
+	provider := Provider{
+		Name: "vbox",
+		Connection: Connection{Endpoint: ts.URL, Authentication: Authentication{
+			Method: AuthPassword, Username: "admin", Password: "${env:OPENINFRA_TEST_PASS}",
+		}},
+		Capabilities: []Capability{{Name: "list", Method: "GET", Endpoint: "/vms"}},
+	}
+
+	// Без WithLocalSecrets переменные окружения не читаются
+	_, err := provider.ExecuteCapability("list", nil)
+	assert.ErrorContains(t, err, "не включены (см. WithLocalSecrets)")
+	assert.Empty(t, pass)
+
+	_, err = provider.ExecuteCapabilityContext(context.Background(), "list", nil, WithLocalSecrets())
+	assert.NoError(t, err)
+	assert.Equal(t, "admin", user)
+	assert.Equal(t, "s3cr3t", pass)
+	assert.Equal(t, "${env:OPENINFRA_TEST_PASS}", provider.Connection.Authentication.Password)
+
+	provider.Connection.Authentication.Password = "${env:OPENINFRA_TEST_MISSING}"
+	_, err = provider.ExecuteCapabilityContext(context.Background(), "list", nil, WithLocalSecrets())
+	assert.ErrorContains(t, err, "переменная окружения OPENINFRA_TEST_MISSING не задана")
+}
+
+func TestAuthenticationRedaction(t *testing.T) {
+	auth := Authentication{
+		Method:   AuthPassword,
+		Username: "admin",
+		Password: "hunter2",
+		APIKey:   "${env:API_KEY}",
+	}
+
+	assert.Equal(t, "{method: password, username: admin, password: ***, api_key: ${env:API_KEY}}", auth.String())
+	for _, format := range []string{"%v", "%+v", "%#v", "%s"} {
+		assert.NotContains(t, fmt.Sprintf(format, auth), "hunter2", format)
+	}
+	assert.NotContains(t, fmt.Sprintf("%+v", Provider{Connection: Connection{Authentication: auth}}), "hunter2")
+
+	spec := &OpenInfraSpec{Providers: map[string]Provider{
+		"vbox": {Name: "vbox", Connection: Connection{Authentication: auth}},
+	}}
+	out, err := GenerateYAML(spec)
+	assert.NoError(t, err)
+	assert.Contains(t, out, "api_key: ${env:API_KEY}")
+}
@@ -0,0 +1,131 @@
+package parser
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CodeUnknownField — ключ, которого нет в формате (строгий режим).
+const CodeUnknownField ErrorCode = "unknown_field"
+
+var extensionKey = regexp.MustCompile(extensionKeys)
+
+// WithStrict включает строгий режим: ключи, которых нет в типах
+// спецификации, считаются ошибкой, а не пропускаются. Ключи расширений
+// вида x-owner и содержимое properties не проверяются. Разбор возвращает
+// ValidationErrors со всеми неизвестными ключами и подсказкой,
+// если ключ похож на известный.
+func WithStrict() Option {
+	return func(o *options) {
+		o.strict = true
+	}
+}
+
+// checkKnownFields проверяет, что все ключи документа root известны
+// типам спецификации.
+func checkKnownFields(root *yaml.Node, fileOf func(*yaml.Node) string) ValidationErrors {
+	c := &fieldChecker{fileOf: fileOf, seen: make(map[*yaml.Node]bool)}
+	if n := resolveAlias(root); n != nil && n.Kind == yaml.MappingNode {
+		c.check(n, reflect.TypeOf(rawSpec{}), yamlPath{})
+	}
+	return c.errs
+}
+
+type fieldChecker struct {
+	fileOf func(*yaml.Node) string
+	// seen — уже проверенные узлы: алиас не даёт повторных ошибок
+	seen map[*yaml.Node]bool
+	errs ValidationErrors
+}
+
+func (c *fieldChecker) check(n *yaml.Node, t reflect.Type, path yamlPath) {
+	n = resolveAlias(n)
+	if n == nil || c.seen[n] {
+		return
+	}
+	c.seen[n] = true
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case t.Kind() == reflect.Struct && n.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, value := n.Content[i], n.Content[i+1]
+			if f, known := yamlFieldByKey(t, key.Value); known {
+				c.check(value, f.Type, path.with(key.Value))
+				continue
+			}
+			if !extensionKey.MatchString(key.Value) {
+				c.report(key, path.with(key.Value), t)
+			}
+		}
+	case (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && n.Kind == yaml.SequenceNode:
+		for i, item := range n.Content {
+			c.check(item, t.Elem(), path.with(i))
+		}
+	case t.Kind() == reflect.Map && n.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			c.check(n.Content[i+1], t.Elem(), path.with(n.Content[i].Value))
+		}
+	}
+}
+
+func (c *fieldChecker) report(key *yaml.Node, path yamlPath, t reflect.Type) {
+	msg := fmt.Sprintf("неизвестное поле %s", key.Value)
+	if s := suggestField(key.Value, t); s != "" {
+		msg += fmt.Sprintf(", возможно, имелось в виду %s", s)
+	}
+	c.errs = append(c.errs, &ValidationError{
+		Code:     CodeUnknownField,
+		Message:  msg,
+		Path:     path.String(),
+		Position: nodePosition(c.fileOf(key), key),
+	})
+}
+
+// suggestField возвращает поле типа t, ближайшее к key по расстоянию
+// Левенштейна, или пустую строку, если похожих полей нет.
+func suggestField(key string, t reflect.Type) string {
+	best, bestDist := "", 0
+	for _, f := range yamlFields(t) {
+		d := levenshtein(key, f.Key)
+		if best == "" || d < bestDist {
+			best, bestDist = f.Key, d
+		}
+	}
+	// Подсказка полезна только для опечаток, а не для любого поля
+	limit := len([]rune(key)) / 3
+	if limit < 2 {
+		limit = 2
+	}
+	if best == "" || bestDist > limit {
+		return ""
+	}
+	return best
+}
+
+// levenshtein возвращает расстояние редактирования между строками a и b.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
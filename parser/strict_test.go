@@ -0,0 +1,84 @@
+package parser
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const typoYAML = `openinfra: 1.1.0
+x-owner: platform
+providers:
+  - name: vbox
+    type: hypervisor
+    conection:
+      host: 10.0.0.5
+    capabilites:
+      - name: start
+  - name: cloud
+    type: cloud
+    connection:
+      protocol: https
+      authentication:
+        metod: bearer
+components:
+  - name: vm
+    type: virtual_machine
+    provider: vbox
+    properties:
+      anything: goes
+    actions:
+      - name: start
+        x-note: ok
+        timeout: 30s
+`
+
+func TestStrictMode(t *testing.T) {
+	// По умолчанию неизвестные ключи пропускаются
+	spec, err := ParseBytes([]byte(typoYAML))
+	require.NoError(t, err)
+	assert.Empty(t, spec.Providers["vbox"].Capabilities)
+
+	_, err = ParseBytes([]byte(typoYAML), WithSource("spec.yaml"), WithStrict())
+	require.Error(t, err)
+
+	var errs ValidationErrors
+	require.True(t, errors.As(err, &errs))
+	assert.Equal(t, `spec.yaml:6:5: неизвестное поле conection, возможно, имелось в виду connection
+spec.yaml:8:5: неизвестное поле capabilites, возможно, имелось в виду capabilities
+spec.yaml:15:9: неизвестное поле metod, возможно, имелось в виду method
+spec.yaml:25:9: неизвестное поле timeout`, err.Error())
+	for _, e := range errs {
+		assert.Equal(t, CodeUnknownField, e.Code)
+	}
+	assert.Equal(t, "providers[0].conection", errs[0].Path)
+}
+
+func TestStrictModeAcceptsValidSpecs(t *testing.T) {
+	_, err := ParseBytes([]byte(mergeBaseYAML), WithStrict())
+	assert.NoError(t, err)
+
+	// Устаревшая форма переводится до проверки и ошибкой не считается
+	_, err = ParseBytes([]byte(legacyYAML), WithStrict())
+	assert.NoError(t, err)
+}
+
+func TestSuggestField(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"capabilites", "capabilities"},
+		{"conection", "connection"},
+		{"Name", "name"},
+		{"dependson", ""},
+		{"flavour", ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, suggestField(tt.key, reflect.TypeOf(Provider{})), tt.key)
+	}
+	assert.Equal(t, "depends_on", suggestField("dependson", reflect.TypeOf(Dependency{})))
+}
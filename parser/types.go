@@ -1,12 +1,32 @@
 package parser
 
+import (
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
 // OpenInfraSpec описывает структуру корневого документа OpenInfra
 type OpenInfraSpec struct {
-	Version      string              `yaml:"openinfra"`
-	Info         Info                `yaml:"info"`
-	Providers    map[string]Provider `yaml:"providers"`
-	Resources    map[string]Resource `yaml:"components"`
-	Dependencies []Dependency        `yaml:"dependencies"`
+	Version      string                 `yaml:"openinfra"`
+	Info         Info                   `yaml:"info"`
+	Variables    map[string]interface{} `yaml:"variables,omitempty"`
+	Providers    map[string]Provider    `yaml:"providers"`
+	Resources    map[string]Resource    `yaml:"components"`
+	Dependencies []Dependency           `yaml:"dependencies,omitempty"`
+
+	// source — метка источника, из которого получена спецификация
+	source string
+	// root — дерево узлов исходного документа (nil, если спецификация
+	// создана не парсером)
+	root *yaml.Node
+	// providerOrder и resourceOrder хранят порядок имён в исходном документе
+	providerOrder []string
+	resourceOrder []string
+	// warnings — предупреждения, полученные при разборе
+	warnings ValidationErrors
+	// files — файлы узлов, подключённых через includes и $ref
+	files map[*yaml.Node]string
 }
 
 // Info содержит общую информацию о спецификации
@@ -28,7 +48,9 @@ type Provider struct {
 	Name         string       `yaml:"name"`
 	Type         string       `yaml:"type"`
 	Connection   Connection   `yaml:"connection"`
-	Capabilities []Capability `yaml:"capabilities"`
+	Capabilities []Capability `yaml:"capabilities,omitempty"`
+	// Pos — место объявления в исходном документе; заполняется парсером
+	Pos Position `yaml:"-"`
 }
 
 type Connection struct {
@@ -39,11 +61,29 @@ type Connection struct {
 	Authentication Authentication `yaml:"authentication"`
 }
 
+// Authentication описывает учётные данные провайдера. Набор используемых
+// полей зависит от Method, см. RegisterAuthenticator. Вместо значений можно
+// указывать ссылки на секреты (${env:NAME}, ${file:/run/secrets/key}), они
+// разрешаются только при выполнении запроса, см. WithLocalSecrets
+// и RegisterSecretResolver.
 type Authentication struct {
 	Method   string `yaml:"method"`
 	Username string `yaml:"username,omitempty"`
 	Password string `yaml:"password,omitempty"`
 	APIKey   string `yaml:"api_key,omitempty"`
+	// Token — токен для метода bearer
+	Token string `yaml:"token,omitempty"`
+	// Header — имя заголовка с ключом для метода header
+	Header string `yaml:"header,omitempty"`
+	// Param — имя параметра запроса с ключом для метода query
+	Param string `yaml:"param,omitempty"`
+	// TokenURL, ClientID, ClientSecret и Scopes используются методом oauth2
+	TokenURL     string   `yaml:"token_url,omitempty"`
+	ClientID     string   `yaml:"client_id,omitempty"`
+	ClientSecret string   `yaml:"client_secret,omitempty"`
+	Scopes       []string `yaml:"scopes,omitempty"`
+	// Secret — ключ подписи для метода hmac
+	Secret string `yaml:"secret,omitempty"`
 }
 
 type Capability struct {
@@ -51,18 +91,63 @@ type Capability struct {
 	Description string      `yaml:"description"`
 	Method      string      `yaml:"method"`
 	Endpoint    string      `yaml:"endpoint"`
-	Parameters  []Parameter `yaml:"parameters"`
+	Parameters  []Parameter `yaml:"parameters,omitempty"`
+	// Timeout ограничивает время выполнения запроса, например 30s
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+	// Response описывает ожидаемый ответ; по нему декодируется JSON
+	Response *ResponseSchema `yaml:"response,omitempty"`
+	// Pos — место объявления в исходном документе; заполняется парсером
+	Pos Position `yaml:"-"`
+}
+
+// ResponseSchema описывает JSON-ответ возможности.
+type ResponseSchema struct {
+	// Type — object или array (массив объектов)
+	Type   string      `yaml:"type"`
+	Fields []Parameter `yaml:"fields,omitempty"`
 }
 
 type Parameter struct {
 	Name     string `yaml:"name"`
 	Type     string `yaml:"type"`
 	Required bool   `yaml:"required"`
+	// In — где передаётся параметр: path, query, header или body.
+	// По умолчанию path, если имя встречается в endpoint как {name},
+	// query для GET, HEAD и DELETE и body для остальных методов.
+	In string `yaml:"in,omitempty"`
+	// Enum — допустимые значения параметра
+	Enum []interface{} `yaml:"enum,omitempty"`
+	// Default — значение, которое подставляется, если параметр не передан
+	Default interface{} `yaml:"default,omitempty"`
 }
 
+// Типы параметров
+const (
+	TypeString  = "string"
+	TypeInteger = "integer"
+	TypeNumber  = "number"
+	TypeBoolean = "boolean"
+	TypeArray   = "array"
+	TypeObject  = "object"
+	TypeEnum    = "enum"
+)
+
+// Расположение параметра в запросе
+const (
+	ParamInPath   = "path"
+	ParamInQuery  = "query"
+	ParamInHeader = "header"
+	ParamInBody   = "body"
+)
+
 type Action struct {
 	Name   string `yaml:"name"`
 	Method string `yaml:"method"`
+	// Capability — имя возможности провайдера, которая выполняет действие.
+	// Если не задано, используется Name.
+	Capability string `yaml:"capability,omitempty"`
+	// Pos — место объявления в исходном документе; заполняется парсером
+	Pos Position `yaml:"-"`
 }
 
 // ResourceDefinition описывает конкретный ресурс
@@ -70,13 +155,17 @@ type Resource struct {
 	Type         string                 `yaml:"type"`
 	Provider     string                 `yaml:"provider"`
 	Name         string                 `yaml:"name"`
-	Properties   map[string]interface{} `yaml:"properties"`
-	Actions      []Action               `yaml:"actions"`
-	Dependencies []Dependency           `yaml:"dependencies"`
+	Properties   map[string]interface{} `yaml:"properties,omitempty"`
+	Actions      []Action               `yaml:"actions,omitempty"`
+	Dependencies []Dependency           `yaml:"dependencies,omitempty"`
+	// Pos — место объявления в исходном документе; заполняется парсером
+	Pos Position `yaml:"-"`
 }
 
 // Dependency описывает зависимости между ресурсами
 type Dependency struct {
 	Resource  string   `yaml:"component"`
-	DependsOn []string `yaml:"depends_on"`
+	DependsOn []string `yaml:"depends_on,omitempty"`
+	// Pos — место объявления в исходном документе; заполняется парсером
+	Pos Position `yaml:"-"`
 }
@@ -0,0 +1,246 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrorCode — машинно-читаемый код ошибки проверки спецификации.
+type ErrorCode string
+
+const (
+	// CodeMissingField — не заполнено обязательное поле
+	CodeMissingField ErrorCode = "missing_field"
+	// CodeDuplicateProvider — несколько провайдеров с одним именем
+	CodeDuplicateProvider ErrorCode = "duplicate_provider"
+	// CodeDuplicateComponent — несколько компонентов с одним именем
+	CodeDuplicateComponent ErrorCode = "duplicate_component"
+	// CodeDuplicateCapability — у провайдера несколько возможностей с одним именем
+	CodeDuplicateCapability ErrorCode = "duplicate_capability"
+	// CodeUnknownProvider — компонент ссылается на несуществующего провайдера
+	CodeUnknownProvider ErrorCode = "unknown_provider"
+	// CodeUnknownComponent — зависимость ссылается на несуществующий компонент
+	CodeUnknownComponent ErrorCode = "unknown_component"
+)
+
+// ValidationError описывает одну проблему в спецификации.
+type ValidationError struct {
+	Code    ErrorCode `json:"code"`
+	Message string    `json:"message"`
+	// Path — путь к проблемному узлу, например components[1].provider
+	Path string `json:"path"`
+	// Position — место в исходном файле, если спецификация получена парсером
+	Position
+}
+
+func (e *ValidationError) Error() string {
+	if e.IsValid() {
+		return fmt.Sprintf("%s: %s", e.Position, e.Message)
+	}
+	if e.Path != "" {
+		return fmt.Sprintf("%s: %s", e.Path, e.Message)
+	}
+	return e.Message
+}
+
+// ValidationErrors — список ошибок проверки.
+type ValidationErrors []*ValidationError
+
+func (errs ValidationErrors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, e := range errs {
+		msgs[i] = e.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Err возвращает nil для пустого списка и сам список в противном случае.
+// Удобно, чтобы не получить ненулевой интерфейс error с пустым срезом.
+func (errs ValidationErrors) Err() error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// Validate выполняет семантическую проверку спецификации: ссылки
+// на провайдеров и компоненты, уникальность имён, обязательные поля.
+// Возвращает пустой список, если ошибок нет.
+func Validate(spec *OpenInfraSpec) ValidationErrors {
+	v := &validator{spec: spec}
+	v.checkDuplicates("providers", CodeDuplicateProvider, "провайдер")
+	v.checkDuplicates("components", CodeDuplicateComponent, "компонент")
+	v.checkProviders()
+	v.checkResources()
+	v.checkDependencies(yamlPath{"dependencies"}, spec.Dependencies, "")
+	return v.errs
+}
+
+type validator struct {
+	spec *OpenInfraSpec
+	errs ValidationErrors
+}
+
+// report добавляет ошибку; позиция берётся из дерева узлов по пути.
+func (v *validator) report(code ErrorCode, path yamlPath, format string, args ...interface{}) {
+	e := &ValidationError{
+		Code:    code,
+		Message: fmt.Sprintf(format, args...),
+		Path:    path.String(),
+	}
+	if v.spec.root != nil {
+		// Если самого узла нет (например, поле не заполнено), указываем
+		// на ближайшего существующего предка.
+		for p := path; ; p = p[:len(p)-1] {
+			if n := lookupNode(v.spec.root, p); n != nil {
+				e.Position = nodePosition(v.spec.fileOf(n), n)
+				break
+			}
+			if len(p) == 0 {
+				break
+			}
+		}
+	}
+	v.errs = append(v.errs, e)
+}
+
+// itemPath возвращает путь к элементу section с именем name: по индексу
+// в исходном документе, а для добавленных программно — по индексу
+// в упорядоченном списке имён.
+func (v *validator) itemPath(section, name string, ordered []string) yamlPath {
+	if v.spec.root != nil {
+		if i := itemIndex(v.spec.root, section, "name", name); i >= 0 {
+			return yamlPath{section, i}
+		}
+	}
+	for i, n := range ordered {
+		if n == name {
+			return yamlPath{section, i}
+		}
+	}
+	return yamlPath{section}
+}
+
+// checkDuplicates ищет повторяющиеся имена в исходном документе:
+// в картах OpenInfraSpec дубликаты уже схлопнуты.
+func (v *validator) checkDuplicates(section string, code ErrorCode, what string) {
+	seq := resolveAlias(mappingValue(v.spec.root, section))
+	if seq == nil || seq.Kind != yaml.SequenceNode {
+		return
+	}
+	seen := make(map[string]int)
+	for i, item := range seq.Content {
+		name := scalarValue(mappingValue(item, "name"))
+		if name == "" {
+			continue
+		}
+		if first, exists := seen[name]; exists {
+			v.report(code, yamlPath{section, i, "name"},
+				"%s %q уже объявлен в %s", what, name, yamlPath{section, first})
+			continue
+		}
+		seen[name] = i
+	}
+}
+
+func (v *validator) checkProviders() {
+	names := v.spec.providerNames()
+	for _, name := range names {
+		p := v.spec.Providers[name]
+		path := v.itemPath("providers", name, names)
+
+		if p.Name == "" {
+			v.report(CodeMissingField, path.with("name"), "у провайдера не указано имя")
+		}
+		if p.Type == "" {
+			v.report(CodeMissingField, path.with("type"), "у провайдера %q не указан тип", name)
+		}
+
+		seen := make(map[string]int)
+		for i, c := range p.Capabilities {
+			capPath := path.with("capabilities", i)
+			if c.Name == "" {
+				v.report(CodeMissingField, capPath.with("name"),
+					"у возможности провайдера %q не указано имя", name)
+				continue
+			}
+			if first, exists := seen[c.Name]; exists {
+				v.report(CodeDuplicateCapability, capPath.with("name"),
+					"возможность %q провайдера %q уже объявлена в %s",
+					c.Name, name, path.with("capabilities", first))
+				continue
+			}
+			seen[c.Name] = i
+		}
+	}
+}
+
+func (v *validator) checkResources() {
+	names := v.spec.resourceNames()
+	for _, name := range names {
+		r := v.spec.Resources[name]
+		path := v.itemPath("components", name, names)
+
+		if r.Name == "" {
+			v.report(CodeMissingField, path.with("name"), "у компонента не указано имя")
+		}
+		if r.Type == "" {
+			v.report(CodeMissingField, path.with("type"), "у компонента %q не указан тип", name)
+		}
+
+		switch {
+		case r.Provider == "":
+			v.report(CodeMissingField, path.with("provider"),
+				"у компонента %q не указан провайдер", name)
+		case !v.hasProvider(r.Provider):
+			v.report(CodeUnknownProvider, path.with("provider"),
+				"компонент %q ссылается на несуществующего провайдера %q", name, r.Provider)
+		}
+
+		v.checkDependencies(path.with("dependencies"), r.Dependencies, name)
+	}
+}
+
+// checkDependencies проверяет список зависимостей. owner — имя компонента,
+// в котором объявлен список; для него поле component можно не указывать.
+func (v *validator) checkDependencies(path yamlPath, deps []Dependency, owner string) {
+	for i, dep := range deps {
+		depPath := path.with(i)
+		component := dep.Resource
+		switch {
+		case component == "" && owner == "":
+			v.report(CodeMissingField, depPath.with("component"), "в зависимости не указан компонент")
+		case component != "" && !v.hasResource(component):
+			v.report(CodeUnknownComponent, depPath.with("component"),
+				"зависимость объявлена для несуществующего компонента %q", component)
+		}
+		if component == "" {
+			component = owner
+		}
+
+		for j, target := range dep.DependsOn {
+			if !v.hasResource(target) {
+				v.report(CodeUnknownComponent, depPath.with("depends_on", j),
+					"компонент %q зависит от несуществующего компонента %q", component, target)
+			}
+		}
+	}
+}
+
+func (v *validator) hasResource(name string) bool {
+	_, exists := v.spec.Resources[name]
+	return exists || v.declared("components", name)
+}
+
+func (v *validator) hasProvider(name string) bool {
+	return v.spec.HasProvider(name) || v.declared("providers", name)
+}
+
+// declared сообщает, что элемент с именем name есть в исходном документе.
+// Его нет в спецификации, только если режим восстановления пропустил
+// элемент, а об этом уже сообщено при разборе.
+func (v *validator) declared(section, name string) bool {
+	return v.spec.root != nil && itemIndex(v.spec.root, section, "name", name) >= 0
+}
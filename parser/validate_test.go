@@ -0,0 +1,88 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const brokenYAML = `openinfra: 1.0.0
+providers:
+  - name: vbox
+    type: virtualbox
+  - name: vbox
+    type: virtualbox
+    capabilities:
+      - name: start_vm
+      - name: start_vm
+components:
+  - name: vm
+    type: virtual_machine
+    provider: missing
+    dependencies:
+      - depends_on: [ghost]
+  - name: net
+    type: network
+    provider: vbox
+dependencies:
+  - component: nobody
+    depends_on:
+      - net
+`
+
+func TestValidateSample(t *testing.T) {
+	spec, err := ParseBytes([]byte(sampleYAML))
+	assert.NoError(t, err)
+
+	errs := Validate(spec)
+	assert.Empty(t, errs)
+	assert.NoError(t, errs.Err())
+}
+
+func TestValidateErrors(t *testing.T) {
+	spec, err := ParseBytes([]byte(brokenYAML), WithSource("broken.yaml"))
+	assert.NoError(t, err)
+
+	errs := Validate(spec)
+	assert.Error(t, errs.Err())
+
+	type short struct {
+		Code   ErrorCode
+		Path   string
+		Line   int
+		Column int
+	}
+	var got []short
+	for _, e := range errs {
+		assert.Equal(t, "broken.yaml", e.File)
+		got = append(got, short{e.Code, e.Path, e.Line, e.Column})
+	}
+
+	assert.Equal(t, []short{
+		{CodeDuplicateProvider, "providers[1].name", 5, 11},
+		{CodeDuplicateCapability, "providers[1].capabilities[1].name", 9, 15},
+		{CodeUnknownProvider, "components[0].provider", 13, 15},
+		{CodeUnknownComponent, "components[0].dependencies[0].depends_on[0]", 15, 22},
+		{CodeUnknownComponent, "dependencies[0].component", 20, 16},
+	}, got)
+
+	assert.Equal(t, `broken.yaml:13:15: компонент "vm" ссылается на несуществующего провайдера "missing"`, errs[2].Error())
+}
+
+func TestValidateWithoutSource(t *testing.T) {
+	spec := &OpenInfraSpec{
+		Providers: map[string]Provider{
+			"aws": {Name: "aws"},
+		},
+		Resources: map[string]Resource{
+			"vm": {Name: "vm", Type: "virtual_machine", Provider: "aws"},
+		},
+	}
+
+	errs := Validate(spec)
+	assert.Len(t, errs, 1)
+	assert.Equal(t, CodeMissingField, errs[0].Code)
+	assert.Equal(t, "providers[0].type", errs[0].Path)
+	assert.False(t, errs[0].IsValid())
+	assert.Equal(t, `providers[0].type: у провайдера "aws" не указан тип`, errs[0].Error())
+}
@@ -0,0 +1,378 @@
+package parser
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// exprPattern находит выражения ${var.name}, ${env.NAME} и
+// ${component.name.path}. Ссылки на секреты ${env:NAME} под него
+// не попадают: они разрешаются только при выполнении запроса.
+// Запись $${...} оставляет выражение как есть, без первого $.
+var exprPattern = regexp.MustCompile(`\$?\$\{(var|env|component)\.([^}]*)\}`)
+
+// interpolate подставляет значения выражений в раздел variables,
+// подключения провайдеров и свойства компонентов. Если выражение занимает
+// всё значение, подставляется узел целиком с его типом (число, список);
+// иначе значение подставляется в строку. Исходное дерево не изменяется.
+//
+// В connection.authentication выражения запрещены: подставленный секрет
+// попал бы в открытом виде в GenerateYAML, слияние и документ. Там нужно
+// использовать ссылки ${env:NAME}, которые разрешаются при выполнении.
+func (o *options) interpolate(root *yaml.Node) (*yaml.Node, error) {
+	body := resolveAlias(root)
+	if body == nil || body.Kind != yaml.MappingNode || (len(o.variables) == 0 && !o.hasExpressions(body)) {
+		return root, nil
+	}
+
+	if o.files == nil {
+		o.files = make(map[*yaml.Node]string)
+	}
+	root = o.copyTree(root)
+	body = resolveAlias(root)
+	if err := o.overrideVariables(body); err != nil {
+		return nil, err
+	}
+
+	in := &interpolator{
+		o:        o,
+		body:     body,
+		resolved: make(map[*yaml.Node]*yaml.Node),
+		active:   make(map[*yaml.Node]bool),
+	}
+	if err := in.resolveKey(body, "variables"); err != nil {
+		return nil, err
+	}
+	for _, p := range sectionItems(body, "providers") {
+		auth := mappingValue(resolveAlias(mappingValue(p, "connection")), "authentication")
+		if n, expr := findExpression(auth, make(map[*yaml.Node]bool)); n != nil {
+			return nil, o.nodeError(n, "выражение %s нельзя использовать в connection.authentication: "+
+				"для секретов используйте ссылку ${env:%s}, она разрешается только при выполнении запроса",
+				expr, secretHint(expr))
+		}
+		if err := in.resolveKey(p, "connection"); err != nil {
+			return nil, err
+		}
+	}
+	for _, c := range sectionItems(body, "components") {
+		if err := in.resolveKey(c, "properties"); err != nil {
+			return nil, err
+		}
+	}
+	return root, nil
+}
+
+// hasExpressions сообщает, есть ли в разделах с подстановкой выражения.
+func (o *options) hasExpressions(body *yaml.Node) bool {
+	nodes := []*yaml.Node{mappingValue(body, "variables")}
+	for _, p := range sectionItems(body, "providers") {
+		nodes = append(nodes, mappingValue(p, "connection"))
+	}
+	for _, c := range sectionItems(body, "components") {
+		nodes = append(nodes, mappingValue(c, "properties"))
+	}
+	for _, n := range nodes {
+		if containsExpression(n, make(map[*yaml.Node]bool)) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsExpression(n *yaml.Node, seen map[*yaml.Node]bool) bool {
+	n = resolveAlias(n)
+	if n == nil || seen[n] {
+		return false
+	}
+	seen[n] = true
+	if n.Kind == yaml.ScalarNode {
+		return exprPattern.MatchString(n.Value)
+	}
+	for _, child := range n.Content {
+		if containsExpression(child, seen) {
+			return true
+		}
+	}
+	return false
+}
+
+// findExpression возвращает первый узел поддерева n с выражением (кроме
+// экранированных $${...}) и само выражение.
+func findExpression(n *yaml.Node, seen map[*yaml.Node]bool) (*yaml.Node, string) {
+	n = resolveAlias(n)
+	if n == nil || seen[n] {
+		return nil, ""
+	}
+	seen[n] = true
+	if n.Kind == yaml.ScalarNode {
+		for _, m := range exprPattern.FindAllStringIndex(n.Value, -1) {
+			if expr := n.Value[m[0]:m[1]]; !strings.HasPrefix(expr, "$$") {
+				return n, expr
+			}
+		}
+		return nil, ""
+	}
+	for _, child := range n.Content {
+		if found, expr := findExpression(child, seen); found != nil {
+			return found, expr
+		}
+	}
+	return nil, ""
+}
+
+// secretHint предлагает имя переменной окружения для ссылки на секрет
+// вместо выражения expr.
+func secretHint(expr string) string {
+	m := exprPattern.FindStringSubmatch(expr)
+	if m[1] == "env" {
+		return m[2]
+	}
+	return "NAME"
+}
+
+// isExpression сообщает, что значение узла целиком состоит из выражения.
+func isExpression(n *yaml.Node) bool {
+	if n.Kind != yaml.ScalarNode || n.ShortTag() != "!!str" {
+		return false
+	}
+	m := exprPattern.FindStringIndex(n.Value)
+	return m != nil && m[0] == 0 && m[1] == len(n.Value) && !strings.HasPrefix(n.Value, "$$")
+}
+
+// overrideVariables записывает значения WithVariables в раздел variables.
+func (o *options) overrideVariables(body *yaml.Node) error {
+	if len(o.variables) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(o.variables))
+	for name := range o.variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	vars, err := ensureKey(body, "variables", yaml.MappingNode)
+	if err != nil {
+		return o.nodeError(vars, "раздел %v", err)
+	}
+	for _, name := range names {
+		n, err := encodeNode(o.variables[name])
+		if err != nil {
+			return err
+		}
+		if i := keyIndex(vars, name); i >= 0 {
+			vars.Content[i+1] = n
+			continue
+		}
+		vars.Content = append(vars.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: name}, n)
+	}
+	return nil
+}
+
+// nodeError возвращает ошибку с позицией узла n.
+func (o *options) nodeError(n *yaml.Node, format string, args ...interface{}) error {
+	return positionError(o.fileOf(n), n, fmt.Errorf(format, args...))
+}
+
+// positionError дополняет ошибку err позицией узла n в файле file.
+func positionError(file string, n *yaml.Node, err error) error {
+	if file == "" {
+		return fmt.Errorf("ошибка: %s: %w", nodePosition(file, n), err)
+	}
+	return fmt.Errorf("ошибка в файле %s: %w", nodePosition(file, n), err)
+}
+
+// interpolator вычисляет выражения. Узлы разрешаются лениво: выражение
+// может ссылаться на переменную или свойство, которые сами содержат
+// выражения.
+type interpolator struct {
+	o    *options
+	body *yaml.Node
+	// resolved — уже вычисленные узлы
+	resolved map[*yaml.Node]*yaml.Node
+	// active и stack — вычисляемые сейчас узлы и выражения для поиска циклов
+	active map[*yaml.Node]bool
+	stack  []string
+}
+
+// resolveKey вычисляет значение ключа key отображения m.
+func (in *interpolator) resolveKey(m *yaml.Node, key string) error {
+	i := keyIndex(m, key)
+	if i < 0 {
+		return nil
+	}
+	n, err := in.resolve(m.Content[i+1])
+	if err != nil {
+		return err
+	}
+	m.Content[i+1] = n
+	return nil
+}
+
+// resolve возвращает узел n с подставленными значениями выражений.
+func (in *interpolator) resolve(n *yaml.Node) (*yaml.Node, error) {
+	if n.Kind == yaml.AliasNode {
+		return in.resolve(n.Alias)
+	}
+	if r, exists := in.resolved[n]; exists {
+		return r, nil
+	}
+	if in.active[n] {
+		return nil, in.o.nodeError(n, "циклическая ссылка: %s", strings.Join(in.stack, " -> "))
+	}
+	in.active[n] = true
+	defer delete(in.active, n)
+
+	r := n
+	switch n.Kind {
+	case yaml.ScalarNode:
+		var err error
+		if r, err = in.scalar(n); err != nil {
+			return nil, err
+		}
+	case yaml.MappingNode, yaml.SequenceNode:
+		for i, child := range n.Content {
+			if n.Kind == yaml.MappingNode && i%2 == 0 {
+				continue
+			}
+			c, err := in.resolve(child)
+			if err != nil {
+				return nil, err
+			}
+			n.Content[i] = c
+		}
+	}
+	in.resolved[n] = r
+	return r, nil
+}
+
+// scalar вычисляет выражения в строке n.
+func (in *interpolator) scalar(n *yaml.Node) (*yaml.Node, error) {
+	if n.ShortTag() != "!!str" {
+		return n, nil
+	}
+	matches := exprPattern.FindAllStringSubmatchIndex(n.Value, -1)
+	if len(matches) == 0 {
+		return n, nil
+	}
+
+	// Выражение на всё значение подставляется узлом с сохранением типа
+	if m := matches[0]; len(matches) == 1 && m[0] == 0 && m[1] == len(n.Value) && n.Value[1] == '{' {
+		target, err := in.evaluate(n, n.Value, n.Value[m[2]:m[3]], n.Value[m[4]:m[5]])
+		if err != nil {
+			return nil, err
+		}
+		c := in.o.copyTree(target)
+		c.Anchor = ""
+		c.Line, c.Column = n.Line, n.Column
+		c.HeadComment, c.LineComment, c.FootComment = n.HeadComment, n.LineComment, n.FootComment
+		in.o.files[c] = in.o.fileOf(n)
+		return c, nil
+	}
+
+	var sb strings.Builder
+	last := 0
+	for _, m := range matches {
+		sb.WriteString(n.Value[last:m[0]])
+		last = m[1]
+		expr := n.Value[m[0]:m[1]]
+		if strings.HasPrefix(expr, "$$") {
+			sb.WriteString(expr[1:])
+			continue
+		}
+
+		target, err := in.evaluate(n, expr, n.Value[m[2]:m[3]], n.Value[m[4]:m[5]])
+		if err != nil {
+			return nil, err
+		}
+		if target.Kind != yaml.ScalarNode {
+			return nil, in.o.nodeError(n, "выражение %s: значение типа %s нельзя подставить в строку",
+				expr, nodeTypeName(target))
+		}
+		sb.WriteString(target.Value)
+	}
+	sb.WriteString(n.Value[last:])
+
+	c := *n
+	c.Value, c.Tag = sb.String(), "!!str"
+	in.o.files[&c] = in.o.fileOf(n)
+	return &c, nil
+}
+
+// evaluate возвращает узел, на который указывает выражение expr вида
+// ${kind.path}, встреченное в узле at.
+func (in *interpolator) evaluate(at *yaml.Node, expr, kind, path string) (*yaml.Node, error) {
+	in.stack = append(in.stack, expr)
+	defer func() { in.stack = in.stack[:len(in.stack)-1] }()
+
+	parts := strings.Split(path, ".")
+	for _, p := range parts {
+		if p == "" {
+			return nil, in.o.nodeError(at, "выражение %s: пустое имя в пути", expr)
+		}
+	}
+
+	switch kind {
+	case "env":
+		value, exists := os.LookupEnv(path)
+		if !exists {
+			return nil, in.o.nodeError(at, "выражение %s: переменная окружения %s не задана", expr, path)
+		}
+		// Значение получает тип по правилам YAML: 8080 — число, true — логическое
+		return &yaml.Node{Kind: yaml.ScalarNode, Value: value}, nil
+
+	case "var":
+		vars := resolveAlias(mappingValue(in.body, "variables"))
+		if mappingValue(vars, parts[0]) == nil {
+			return nil, in.o.nodeError(at, "выражение %s: переменная %s не объявлена в разделе variables", expr, parts[0])
+		}
+		return in.walk(at, expr, vars, parts)
+
+	default: // component
+		_, c := findItem(mappingValue(in.body, "components"), "name", parts[0])
+		if c == nil {
+			return nil, in.o.nodeError(at, "выражение %s: компонент %q не найден", expr, parts[0])
+		}
+		if len(parts) == 1 {
+			return nil, in.o.nodeError(at, "выражение %s: не указано поле компонента, например "+
+				"${component.%s.properties.name}", expr, parts[0])
+		}
+		return in.walk(at, expr, c, parts[1:])
+	}
+}
+
+// walk спускается от узла n по ключам и индексам path и возвращает
+// вычисленный узел.
+func (in *interpolator) walk(at *yaml.Node, expr string, n *yaml.Node, path []string) (*yaml.Node, error) {
+	for i, p := range path {
+		n = resolveAlias(n)
+		if n.Kind == yaml.ScalarNode {
+			// Промежуточное значение само может быть выражением
+			r, err := in.resolve(n)
+			if err != nil {
+				return nil, err
+			}
+			n = r
+		}
+
+		var next *yaml.Node
+		switch n.Kind {
+		case yaml.MappingNode:
+			next = mappingValue(n, p)
+		case yaml.SequenceNode:
+			if idx, err := strconv.Atoi(p); err == nil && idx >= 0 && idx < len(n.Content) {
+				next = n.Content[idx]
+			}
+		}
+		if next == nil {
+			return nil, in.o.nodeError(at, "выражение %s: нет поля %s", expr, strings.Join(path[:i+1], "."))
+		}
+		n = next
+	}
+	return in.resolve(n)
+}
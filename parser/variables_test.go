@@ -0,0 +1,207 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const variablesYAML = `openinfra: 1.1.0
+variables:
+  domain: lab.example.com
+  api_port: 8443
+  subnet: 10.20.0.0/24
+  dns: [10.20.0.2, 10.20.0.3]
+  network:
+    gateway: 10.20.0.1
+  api_url: https://api.${var.domain}:${var.api_port}
+providers:
+  - name: cloud
+    type: cloud
+    connection:
+      protocol: https
+      host: api.${var.domain}
+      port: ${var.api_port}
+      authentication:
+        method: bearer
+        token: ${env:CLOUD_TOKEN}
+components:
+  - name: local_network
+    type: network
+    provider: cloud
+    properties:
+      cidr: ${var.subnet}
+      gateway: ${var.network.gateway}
+      dns_servers: ${var.dns}
+      primary_dns: ${var.dns.0}
+      region: ${env.OPENINFRA_TEST_REGION}
+      size: ${env.OPENINFRA_TEST_SIZE}
+  - name: vm
+    type: virtual_machine
+    provider: cloud
+    properties:
+      network: ${component.local_network.properties.cidr}
+      url: ${var.api_url}/vms
+      literal: $${var.domain}
+`
+
+func TestInterpolation(t *testing.T) {
+	t.Setenv("OPENINFRA_TEST_REGION", "eu-north")
+	t.Setenv("OPENINFRA_TEST_SIZE", "20")
+
+	spec, err := ParseBytes([]byte(variablesYAML))
+	require.NoError(t, err)
+
+	conn := spec.Providers["cloud"].Connection
+	assert.Equal(t, "api.lab.example.com", conn.Host)
+	assert.Equal(t, 8443, conn.Port)
+	// Ссылка на секрет не подставляется при разборе
+	assert.Equal(t, "${env:CLOUD_TOKEN}", conn.Authentication.Token)
+
+	assert.Equal(t, map[string]interface{}{
+		"cidr":        "10.20.0.0/24",
+		"gateway":     "10.20.0.1",
+		"dns_servers": []interface{}{"10.20.0.2", "10.20.0.3"},
+		"primary_dns": "10.20.0.2",
+		"region":      "eu-north",
+		"size":        20,
+	}, spec.Resources["local_network"].Properties)
+	assert.Equal(t, map[string]interface{}{
+		"network": "10.20.0.0/24",
+		"url":     "https://api.lab.example.com:8443/vms",
+		"literal": "${var.domain}",
+	}, spec.Resources["vm"].Properties)
+
+	assert.Equal(t, "https://api.lab.example.com:8443", spec.Variables["api_url"])
+}
+
+func TestInterpolationWithVariables(t *testing.T) {
+	t.Setenv("OPENINFRA_TEST_REGION", "eu-north")
+	t.Setenv("OPENINFRA_TEST_SIZE", "20")
+
+	spec, err := ParseBytes([]byte(variablesYAML), WithVariables(map[string]interface{}{
+		"domain":   "prod.example.com",
+		"api_port": 443,
+		"extra":    true,
+	}))
+	require.NoError(t, err)
+
+	conn := spec.Providers["cloud"].Connection
+	assert.Equal(t, "api.prod.example.com", conn.Host)
+	assert.Equal(t, 443, conn.Port)
+	assert.Equal(t, "https://api.prod.example.com:443/vms", spec.Resources["vm"].Properties["url"])
+	assert.Equal(t, true, spec.Variables["extra"])
+}
+
+func TestInterpolationKeepsDocument(t *testing.T) {
+	doc, err := ParseDocument([]byte(variablesYAML))
+	require.NoError(t, err)
+	before := documentText(t, doc)
+
+	t.Setenv("OPENINFRA_TEST_REGION", "eu-north")
+	t.Setenv("OPENINFRA_TEST_SIZE", "20")
+	_, err = doc.Spec()
+	require.NoError(t, err)
+	assert.Equal(t, before, documentText(t, doc))
+}
+
+func TestInterpolationErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		err  string
+	}{
+		{
+			name: "Необъявленная переменная",
+			yaml: "components:\n  - name: vm\n    properties:\n      cpu: ${var.cpu}\n",
+			err:  "ошибка в файле spec.yaml:4:12: выражение ${var.cpu}: переменная cpu не объявлена в разделе variables",
+		},
+		{
+			name: "Нет поля переменной",
+			yaml: "variables:\n  net: {cidr: 10.0.0.0/8}\ncomponents:\n  - name: vm\n    properties:\n      gw: ${var.net.gateway}\n",
+			err:  "ошибка в файле spec.yaml:6:11: выражение ${var.net.gateway}: нет поля net.gateway",
+		},
+		{
+			name: "Переменная окружения не задана",
+			yaml: "providers:\n  - name: p\n    connection:\n      host: ${env.OPENINFRA_TEST_UNSET}\n",
+			err:  "ошибка в файле spec.yaml:4:13: выражение ${env.OPENINFRA_TEST_UNSET}: переменная окружения OPENINFRA_TEST_UNSET не задана",
+		},
+		{
+			name: "Неизвестный компонент",
+			yaml: "components:\n  - name: vm\n    properties:\n      net: ${component.net.properties.cidr}\n",
+			err:  "ошибка в файле spec.yaml:4:12: выражение ${component.net.properties.cidr}: компонент \"net\" не найден",
+		},
+		{
+			name: "Нет поля компонента",
+			yaml: "components:\n  - name: net\n  - name: vm\n    properties:\n      net: ${component.net.properties.cidr}\n",
+			err:  "ошибка в файле spec.yaml:5:12: выражение ${component.net.properties.cidr}: нет поля properties",
+		},
+		{
+			name: "Список в строке",
+			yaml: "variables:\n  dns: [a, b]\ncomponents:\n  - name: vm\n    properties:\n      dns: servers ${var.dns}\n",
+			err:  "ошибка в файле spec.yaml:6:12: выражение ${var.dns}: значение типа array нельзя подставить в строку",
+		},
+		{
+			name: "Выражение в authentication",
+			yaml: "providers:\n  - name: p\n    connection:\n      authentication:\n        password: ${env.OPENINFRA_TEST_PASS}\n",
+			err: "ошибка в файле spec.yaml:5:19: выражение ${env.OPENINFRA_TEST_PASS} нельзя использовать в connection.authentication: " +
+				"для секретов используйте ссылку ${env:OPENINFRA_TEST_PASS}, она разрешается только при выполнении запроса",
+		},
+		{
+			name: "Цикл",
+			yaml: "variables:\n  a: ${var.b}\n  b: x${var.a}\n",
+			err:  "ошибка в файле spec.yaml:2:6: циклическая ссылка: ${var.b} -> ${var.a}",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseBytes([]byte(tt.yaml), WithSource("spec.yaml"))
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func TestInterpolationKeepsSecrets(t *testing.T) {
+	t.Setenv("OPENINFRA_TEST_PASS", "s3cret")
+
+	data := []byte(`variables:
+  password: ${env.OPENINFRA_TEST_PASS}
+providers:
+  - name: p
+    connection:
+      host: api.example.com
+      authentication:
+        username: admin
+        password: ${var.password}
+`)
+	_, err := ParseBytes(data)
+	assert.ErrorContains(t, err, "выражение ${var.password} нельзя использовать в connection.authentication")
+	doc, err := ParseDocument(data)
+	require.NoError(t, err)
+	_, err = doc.Spec()
+	assert.Error(t, err)
+
+	// Ссылка на секрет остаётся ссылкой и в сгенерированном YAML
+	data = []byte(strings.Replace(string(data[strings.Index(string(data), "providers:"):]),
+		"${var.password}", "${env:OPENINFRA_TEST_PASS}", 1))
+	spec, err := ParseBytes(data)
+	require.NoError(t, err)
+	out, err := GenerateYAML(spec)
+	require.NoError(t, err)
+	assert.NotContains(t, out, "s3cret")
+	assert.Contains(t, out, "password: ${env:OPENINFRA_TEST_PASS}")
+}
+
+func TestValidateSchemaExpressions(t *testing.T) {
+	errs, err := ValidateSchema([]byte(variablesYAML))
+	require.NoError(t, err)
+	assert.Empty(t, errs)
+
+	errs, err = ValidateSchema([]byte("openinfra: 1.1.0\nproviders:\n  - name: p\n    type: t\n    connection:\n      port: port-${var.n}\n"))
+	require.NoError(t, err)
+	require.Len(t, errs, 1)
+	assert.Equal(t, "providers[0].connection.port", errs[0].Path)
+}
@@ -0,0 +1,252 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Version — семантическая версия формата из поля openinfra.
+// Совместимость определяется старшей (Major) и младшей (Minor) частью,
+// Patch и суффиксы пререлиза на разбор не влияют.
+type Version struct {
+	Major int
+	Minor int
+	Patch int
+	// Pre — суффикс пререлиза без дефиса, например rc.1
+	Pre string
+}
+
+var versionRe = regexp.MustCompile(`^(\d+)(?:\.(\d+))?(?:\.(\d+))?(?:-([0-9A-Za-z.-]+))?(?:\+[0-9A-Za-z.-]+)?$`)
+
+// ParseVersion разбирает версию вида MAJOR[.MINOR[.PATCH]][-PRE][+BUILD].
+// Недостающие части считаются нулевыми: 1.1 равно 1.1.0.
+func ParseVersion(s string) (Version, error) {
+	m := versionRe.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return Version{}, fmt.Errorf("некорректная версия %q: ожидается MAJOR.MINOR.PATCH", s)
+	}
+	var v Version
+	for i, dst := range []*int{&v.Major, &v.Minor, &v.Patch} {
+		if m[i+1] == "" {
+			continue
+		}
+		n, err := strconv.Atoi(m[i+1])
+		if err != nil {
+			return Version{}, fmt.Errorf("некорректная версия %q: %w", s, err)
+		}
+		*dst = n
+	}
+	v.Pre = m[4]
+	return v, nil
+}
+
+// String возвращает версию в виде MAJOR.MINOR.PATCH[-PRE].
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Pre != "" {
+		s += "-" + v.Pre
+	}
+	return s
+}
+
+// Compare сравнивает версии по Major и Minor: возвращает -1, 0 или 1.
+func (v Version) Compare(other Version) int {
+	switch {
+	case v.Major != other.Major:
+		return sign(v.Major - other.Major)
+	case v.Minor != other.Minor:
+		return sign(v.Minor - other.Minor)
+	}
+	return 0
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
+
+// MigrationFunc обновляет дерево документа до версии, для которой
+// она зарегистрирована, с предыдущей зарегистрированной версии.
+type MigrationFunc func(root *yaml.Node) error
+
+type specVersion struct {
+	version Version
+	migrate MigrationFunc
+}
+
+var (
+	versionsMu sync.RWMutex
+	// versions — поддерживаемые версии формата по возрастанию
+	versions = []specVersion{
+		{version: Version{Major: 1, Minor: 0}},
+		{version: Version{Major: 1, Minor: 1}, migrate: migrateLegacyShape},
+	}
+)
+
+// RegisterVersion добавляет поддерживаемую версию формата. migrate
+// обновляет документ предыдущей версии до v; для первой версии старшей
+// части её можно не указывать. Повторная регистрация заменяет миграцию.
+func RegisterVersion(v Version, migrate MigrationFunc) {
+	versionsMu.Lock()
+	defer versionsMu.Unlock()
+
+	for i := range versions {
+		if versions[i].version.Compare(v) == 0 {
+			versions[i].migrate = migrate
+			return
+		}
+	}
+	versions = append(versions, specVersion{version: v, migrate: migrate})
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].version.Compare(versions[j].version) < 0
+	})
+}
+
+// SupportedVersions возвращает поддерживаемые версии формата по возрастанию.
+func SupportedVersions() []Version {
+	versionsMu.RLock()
+	defer versionsMu.RUnlock()
+
+	out := make([]Version, len(versions))
+	for i, sv := range versions {
+		out[i] = sv.version
+	}
+	return out
+}
+
+// CurrentVersion возвращает текущую версию формата — последнюю
+// из поддерживаемых. К ней приводятся документы при разборе.
+func CurrentVersion() Version {
+	versionsMu.RLock()
+	defer versionsMu.RUnlock()
+	return versions[len(versions)-1].version
+}
+
+// migrate проверяет версию документа, переводит устаревшую форму
+// connection_details и действий-строк в текущую (с предупреждениями,
+// независимо от версии) и применяет миграции до текущей версии.
+// Если inPlace не задан и изменения нужны, они выполняются над копией
+// дерева, и возвращается эта копия; исходное дерево не меняется.
+func (o *options) migrate(root *yaml.Node, inPlace bool) (*yaml.Node, ValidationErrors, error) {
+	declared, pending, err := o.pendingMigrations(root)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(pending) == 0 && !hasLegacyShape(root) {
+		return root, nil, nil
+	}
+
+	if !inPlace {
+		root = o.copyTree(root)
+	}
+	warnings, err := rewriteLegacy(root, o.fileOf)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, sv := range pending {
+		if err := sv.migrate(root); err != nil {
+			return nil, nil, o.versionError(fmt.Errorf("миграция с версии %s на %d.%d: %w",
+				declared, sv.version.Major, sv.version.Minor, err))
+		}
+	}
+	return root, warnings, nil
+}
+
+// pendingMigrations проверяет версию документа и возвращает её вместе
+// с версиями, миграции которых нужно применить.
+func (o *options) pendingMigrations(root *yaml.Node) (string, []specVersion, error) {
+	declared := scalarValue(mappingValue(root, "openinfra"))
+	if declared == "" {
+		// Версия не указана — документ считается документом текущей версии
+		return "", nil, nil
+	}
+
+	v, err := ParseVersion(declared)
+	if err != nil {
+		return "", nil, o.versionError(err)
+	}
+
+	versionsMu.RLock()
+	defer versionsMu.RUnlock()
+
+	current := versions[len(versions)-1].version
+	if v.Major != current.Major || v.Compare(versions[0].version) < 0 {
+		return "", nil, o.versionError(fmt.Errorf("версия %s не поддерживается, поддерживаемые версии: %s",
+			declared, supportedList()))
+	}
+	if v.Minor > current.Minor {
+		// Документ может использовать возможности, о которых парсер не знает
+		return "", nil, o.versionError(fmt.Errorf("версия %s новее поддерживаемой %d.%d, обновите openinfra",
+			declared, current.Major, current.Minor))
+	}
+
+	var pending []specVersion
+	for _, sv := range versions {
+		if sv.migrate != nil && sv.version.Compare(v) > 0 {
+			pending = append(pending, sv)
+		}
+	}
+	return declared, pending, nil
+}
+
+// supportedList перечисляет поддерживаемые версии в виде 1.0, 1.1.
+// Вызывается под versionsMu.
+func supportedList() string {
+	names := make([]string, len(versions))
+	for i, sv := range versions {
+		names[i] = fmt.Sprintf("%d.%d", sv.version.Major, sv.version.Minor)
+	}
+	return strings.Join(names, ", ")
+}
+
+// versionError оборачивает ошибку проверки версии с указанием источника.
+func (o *options) versionError(err error) error {
+	if o.source != "" {
+		return fmt.Errorf("ошибка в поле openinfra файла %s: %w", o.source, err)
+	}
+	return fmt.Errorf("ошибка в поле openinfra: %w", err)
+}
+
+// copyTree копирует дерево узлов и переносит на копии сведения о файлах
+// узлов, подключённых через includes.
+func (o *options) copyTree(root *yaml.Node) *yaml.Node {
+	seen := make(map[*yaml.Node]*yaml.Node)
+	c := copyNode(root, seen)
+	for n, cn := range seen {
+		if file, exists := o.files[n]; exists {
+			o.files[cn] = file
+		}
+	}
+	return c
+}
+
+// copyNode копирует дерево узлов, сохраняя алиасы внутри копии.
+func copyNode(n *yaml.Node, seen map[*yaml.Node]*yaml.Node) *yaml.Node {
+	if n == nil {
+		return nil
+	}
+	if c, exists := seen[n]; exists {
+		return c
+	}
+	c := *n
+	seen[n] = &c
+	c.Alias = copyNode(n.Alias, seen)
+	if n.Content != nil {
+		c.Content = make([]*yaml.Node, len(n.Content))
+		for i, child := range n.Content {
+			c.Content[i] = copyNode(child, seen)
+		}
+	}
+	return &c
+}
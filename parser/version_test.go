@@ -0,0 +1,99 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestParseVersion(t *testing.T) {
+	cases := map[string]Version{
+		"1":              {Major: 1},
+		"1.1":            {Major: 1, Minor: 1},
+		"1.0.3":          {Major: 1, Patch: 3},
+		"2.1.0-rc.1":     {Major: 2, Minor: 1, Pre: "rc.1"},
+		"1.2.3+build.42": {Major: 1, Minor: 2, Patch: 3},
+	}
+	for in, want := range cases {
+		got, err := ParseVersion(in)
+		assert.NoError(t, err, in)
+		assert.Equal(t, want, got, in)
+	}
+
+	for _, in := range []string{"", "v1.0.0", "1.x", "1.0.0.0", "latest"} {
+		_, err := ParseVersion(in)
+		assert.Error(t, err, in)
+	}
+
+	assert.Equal(t, "2.1.0-rc.1", Version{Major: 2, Minor: 1, Pre: "rc.1"}.String())
+}
+
+func TestVersionCompare(t *testing.T) {
+	v := func(s string) Version {
+		parsed, err := ParseVersion(s)
+		require.NoError(t, err)
+		return parsed
+	}
+	assert.Equal(t, 0, v("1.1.0").Compare(v("1.1.7")))
+	assert.Equal(t, -1, v("1.0.9").Compare(v("1.1.0")))
+	assert.Equal(t, 1, v("2.0.0").Compare(v("1.9.0")))
+}
+
+func TestCurrentVersion(t *testing.T) {
+	assert.Equal(t, Version{Major: 1, Minor: 1}, CurrentVersion())
+	assert.Equal(t, []Version{{Major: 1}, {Major: 1, Minor: 1}}, SupportedVersions())
+}
+
+func TestParseUnsupportedVersion(t *testing.T) {
+	_, err := ParseBytes([]byte("openinfra: 2.0.0\n"), WithSource("new.yaml"))
+	assert.EqualError(t, err,
+		"ошибка в поле openinfra файла new.yaml: версия 2.0.0 не поддерживается, поддерживаемые версии: 1.0, 1.1")
+
+	_, err = ParseBytes([]byte("openinfra: 0.9.0\n"))
+	assert.ErrorContains(t, err, "версия 0.9.0 не поддерживается")
+
+	_, err = ParseBytes([]byte("openinfra: 1.9.0\n"), WithSource("new.yaml"))
+	assert.EqualError(t, err, "ошибка в поле openinfra файла new.yaml: версия 1.9.0 новее поддерживаемой 1.1, обновите openinfra")
+
+	_, err = ParseBytes([]byte("openinfra: latest\n"))
+	assert.ErrorContains(t, err, `ошибка в поле openinfra: некорректная версия "latest"`)
+}
+
+func TestParseSupportedVersions(t *testing.T) {
+	for _, v := range []string{"1.0.0", "1.1.0", "1.1", "1.1.7", "1.0"} {
+		spec, err := ParseBytes([]byte("openinfra: " + v + "\n"))
+		require.NoError(t, err, v)
+		// Версия документа сохраняется в том виде, в каком она указана
+		assert.Equal(t, v, spec.Version)
+	}
+
+	spec, err := ParseBytes([]byte("info:\n  title: no version\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "", spec.Version)
+}
+
+func TestRegisterVersion(t *testing.T) {
+	saved := append([]specVersion(nil), versions...)
+	defer func() { versions = saved }()
+
+	RegisterVersion(Version{Major: 1, Minor: 2}, func(root *yaml.Node) error {
+		// Миграция помечает документ, чтобы проверить, что она применена
+		info, err := ensureKey(resolveAlias(root), "info", yaml.MappingNode)
+		if err != nil {
+			return err
+		}
+		info.Content = append(info.Content, legacyScalar("title"), legacyScalar("migrated"))
+		return nil
+	})
+	assert.Equal(t, Version{Major: 1, Minor: 2}, CurrentVersion())
+
+	spec, err := ParseBytes([]byte("openinfra: 1.0.0\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "migrated", spec.Info.Title)
+
+	spec, err = ParseBytes([]byte("openinfra: 1.2.0\ninfo:\n  title: current\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "current", spec.Info.Title)
+}